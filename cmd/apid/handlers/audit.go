@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/audit"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+// Audit represents the audit log API method handler set.
+type Audit struct {
+	MasterDB *db.DB
+}
+
+type GetAudit struct {
+	Entries []audit.Entry `json:"entries"`
+	Total   int           `json:"total"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+}
+
+// getAudit returns the audit log newest first, filtered by the actor, method,
+// route, since and until query parameters. Times are RFC 3339.
+func (a *Audit) getAudit(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	pageLimit, err := queryInt(r, "limit", 50)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	since, err := queryTime(r, "since")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	until, err := queryTime(r, "until")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	q := audit.Query{
+		Actor:  r.URL.Query().Get("actor"),
+		Method: r.URL.Query().Get("method"),
+		Route:  r.URL.Query().Get("route"),
+		Since:  since,
+		Until:  until,
+		Limit:  pageLimit,
+		Offset: offset,
+	}
+	entries, total, err := audit.List(ctx, a.MasterDB, q)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	resp := GetAudit{
+		Entries: entries,
+		Total:   total,
+		Limit:   pageLimit,
+		Offset:  offset,
+	}
+
+	rest.Respond(ctx, w, resp, http.StatusOK)
+	return nil
+}
+
+// queryTime reads the query parameter name as an RFC 3339 time, the zero
+// time if it's missing.
+func queryTime(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, rest.InvalidError{{Fld: name, Err: "must be an RFC 3339 time"}}
+	}
+
+	return t, nil
+}
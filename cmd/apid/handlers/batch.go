@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Batch operation result statuses. When an operation fails the ones before
+// it are rolled back and the ones after it skipped.
+const (
+	BatchOK         = "ok"
+	BatchFailed     = "failed"
+	BatchRolledBack = "rolled_back"
+	BatchSkipped    = "skipped"
+)
+
+// Batch represents the batch operations API method handler set.
+type Batch struct {
+	MasterDB *db.DB
+}
+
+type PostBatch struct {
+	Operations []PostBatchOperation `json:"operations"`
+}
+
+func (b PostBatch) Validate() error {
+	return validation.ValidateStruct(&b, b.Rules()...)
+}
+
+func (b *PostBatch) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&b.Operations, validation.Required, validation.Length(1, user.MaxBatch)),
+	}
+}
+
+// PostBatchOperation is a deposit, withdraw or transfer. To is only set for
+// transfers.
+type PostBatchOperation struct {
+	Op     string `json:"op"`
+	UserID string `json:"user_id"`
+	To     string `json:"to,omitempty"`
+	Amount int64  `json:"amount"`
+}
+
+func (o PostBatchOperation) Validate() error {
+	return validation.ValidateStruct(&o, o.Rules()...)
+}
+
+func (o *PostBatchOperation) Rules() []*validation.FieldRules {
+	var toRules []validation.Rule
+	if o.Op == ledger.TypeTransfer {
+		toRules = append(toRules, validation.Required)
+	}
+
+	return []*validation.FieldRules{
+		validation.Field(&o.Op, validation.Required, validation.In(ledger.TypeDeposit, ledger.TypeWithdraw, ledger.TypeTransfer)),
+		validation.Field(&o.UserID, validation.Required),
+		validation.Field(&o.To, toRules...),
+		validation.Field(&o.Amount, validation.Required, validation.Min(1)),
+	}
+}
+
+// BatchResult is the outcome of the operation at Index.
+type BatchResult struct {
+	Index       int                 `json:"index"`
+	Op          string              `json:"op"`
+	Status      string              `json:"status"`
+	Transaction *ledger.Transaction `json:"transaction,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	Code        string              `json:"code,omitempty"`
+}
+
+// PostBatchResponse gives a result per operation. Committed is false when
+// an operation failed and nothing was applied.
+type PostBatchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// postBatch applies every operation or none. When one fails the response
+// has the status its error would get on its own and says which one it was.
+func (b *Batch) postBatch(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var batch PostBatch
+	err := rest.Unmarshal(r.Body, &batch)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	ops := make([]user.Operation, len(batch.Operations))
+	for i, o := range batch.Operations {
+		ops[i] = user.Operation{
+			Op:     o.Op,
+			UserID: o.UserID,
+			To:     o.To,
+			Amount: o.Amount,
+		}
+	}
+
+	ts, err := user.ApplyBatch(ctx, b.MasterDB, ops)
+	if err != nil {
+		berr, ok := errors.Cause(err).(user.BatchError)
+		if !ok {
+			return errors.Wrap(err, "")
+		}
+
+		status := rest.StatusOf(berr.Err)
+		if status == http.StatusInternalServerError {
+			return errors.Wrap(err, "")
+		}
+
+		resp := PostBatchResponse{
+			Results: make([]BatchResult, len(ops)),
+		}
+		for i, o := range ops {
+			res := BatchResult{Index: i, Op: o.Op}
+			switch {
+			case i < berr.Index:
+				res.Status = BatchRolledBack
+			case i == berr.Index:
+				res.Status = BatchFailed
+				res.Error = rest.MessageOf(berr.Err)
+				res.Code = rest.CodeOf(berr.Err)
+			default:
+				res.Status = BatchSkipped
+			}
+			resp.Results[i] = res
+		}
+
+		rest.Respond(ctx, w, resp, status)
+		return nil
+	}
+
+	resp := PostBatchResponse{
+		Committed: true,
+		Results:   make([]BatchResult, len(ts)),
+	}
+	for i := range ts {
+		resp.Results[i] = BatchResult{
+			Index:       i,
+			Op:          ops[i].Op,
+			Status:      BatchOK,
+			Transaction: &ts[i],
+		}
+	}
+
+	rest.Respond(ctx, w, resp, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Bet represents the betting API method handler set.
+type Bet struct {
+	MasterDB *db.DB
+}
+
+// PostBet places a bet. Odds are decimal odds in hundredths, 250 is 2.50.
+type PostBet struct {
+	ID        string `json:"id"`
+	Market    string `json:"market"`
+	Selection string `json:"selection"`
+	Stake     int64  `json:"stake"`
+	Odds      int64  `json:"odds"`
+}
+
+func (a PostBet) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostBet) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.ID, validation.Required),
+		validation.Field(&a.Market, validation.Required),
+		validation.Field(&a.Selection, validation.Required),
+		validation.Field(&a.Stake, validation.Required),
+		validation.Field(&a.Stake, validation.Min(1)),
+		validation.Field(&a.Odds, validation.Required),
+		validation.Field(&a.Odds, validation.Min(101)),
+	}
+}
+
+var outcomes = []interface{}{user.BetWin, user.BetLose, user.BetVoid, user.BetPush}
+
+type PostBetSettle struct {
+	Outcome string `json:"outcome"`
+}
+
+func (a PostBetSettle) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostBetSettle) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.Outcome, validation.Required),
+		validation.Field(&a.Outcome, validation.In(outcomes...)),
+	}
+}
+
+// PostMarketSettle maps each selection of the market to its outcome.
+type PostMarketSettle struct {
+	Outcomes map[string]string `json:"outcomes"`
+}
+
+func (a PostMarketSettle) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostMarketSettle) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.Outcomes, validation.Required),
+		validation.Field(&a.Outcomes, validation.By(func(value interface{}) error {
+			for _, o := range value.(map[string]string) {
+				if err := validation.In(outcomes...).Validate(o); err != nil {
+					return err
+				}
+			}
+			return nil
+		})),
+	}
+}
+
+func (b *Bet) postBet(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var betPlace PostBet
+	err := rest.Unmarshal(r.Body, &betPlace)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	bet, err := user.PlaceBet(ctx, b.MasterDB, user.Bet{
+		UserID:    betPlace.ID,
+		Market:    betPlace.Market,
+		Selection: betPlace.Selection,
+		Stake:     betPlace.Stake,
+		Odds:      betPlace.Odds,
+	})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, bet, http.StatusOK)
+	return nil
+}
+
+func (b *Bet) getBet(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	bet, err := user.GetBetByID(ctx, b.MasterDB, params["betID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, bet, http.StatusOK)
+	return nil
+}
+
+func (b *Bet) postBetSettle(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var betSettle PostBetSettle
+	err := rest.Unmarshal(r.Body, &betSettle)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	bet, err := user.SettleBet(ctx, b.MasterDB, params["betID"], betSettle.Outcome)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, bet, http.StatusOK)
+	return nil
+}
+
+func (b *Bet) postMarketSettle(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var marketSettle PostMarketSettle
+	err := rest.Unmarshal(r.Body, &marketSettle)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	bets, err := user.SettleMarket(ctx, b.MasterDB, params["market"], marketSettle.Outcomes)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, bets, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Bonus represents the bonus API method handler set.
+type Bonus struct {
+	MasterDB *db.DB
+}
+
+// PostBonus grants a bonus of Amount that has to be wagered Wagering times
+// before ExpiresAt.
+type PostBonus struct {
+	ID        string    `json:"id"`
+	Amount    int64     `json:"amount"`
+	Wagering  int64     `json:"wagering"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (a PostBonus) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostBonus) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.ID, validation.Required),
+		validation.Field(&a.Amount, validation.Required),
+		validation.Field(&a.Amount, validation.Min(1)),
+		validation.Field(&a.Wagering, validation.Required),
+		validation.Field(&a.Wagering, validation.Min(1)),
+		validation.Field(&a.ExpiresAt, validation.Required),
+		validation.Field(&a.ExpiresAt, validation.Min(time.Now())),
+	}
+}
+
+func (b *Bonus) postBonus(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var bonusGrant PostBonus
+	err := rest.Unmarshal(r.Body, &bonusGrant)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	bonus, err := user.GrantBonus(ctx, b.MasterDB, bonusGrant.ID, bonusGrant.Amount, bonusGrant.Wagering, bonusGrant.ExpiresAt)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, bonus, http.StatusOK)
+	return nil
+}
+
+func (b *Bonus) getUserBonuses(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	bonuses, err := user.ListBonusesByID(ctx, b.MasterDB, params["userID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, bonuses, http.StatusOK)
+	return nil
+}
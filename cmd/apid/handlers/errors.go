@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/timurguseynov/go-wallet-api/internal/fee"
+	"github.com/timurguseynov/go-wallet-api/internal/importer"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/limit"
+	"github.com/timurguseynov/go-wallet-api/internal/reconcile"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/risk"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// init maps the domain errors handlers pass on to their response statuses and
+// error codes, so handlers can return them like any other error. Clients
+// match the codes, never change one.
+func init() {
+	rest.RegisterError(user.ErrNotFound, http.StatusNotFound, "USER_NOT_FOUND")
+	rest.RegisterError(user.ErrInsufficientFunds, http.StatusPaymentRequired, "INSUFFICIENT_FUNDS")
+	rest.RegisterError(user.ErrSameAccount, http.StatusUnprocessableEntity, "SAME_ACCOUNT")
+	rest.RegisterError(user.ErrAccountFrozen, http.StatusConflict, "ACCOUNT_FROZEN")
+	rest.RegisterError(user.ErrAccountSuspended, http.StatusConflict, "ACCOUNT_SUSPENDED")
+	rest.RegisterError(user.ErrAccountClosed, http.StatusConflict, "ACCOUNT_CLOSED")
+	rest.RegisterError(user.ErrInvalidTransition, http.StatusConflict, "INVALID_STATUS_TRANSITION")
+	rest.RegisterError(user.ErrBalanceNotZero, http.StatusConflict, "BALANCE_NOT_ZERO")
+	rest.RegisterError(user.ErrEmailTaken, http.StatusConflict, "EMAIL_TAKEN")
+	rest.RegisterError(user.ErrHoldNotFound, http.StatusNotFound, "HOLD_NOT_FOUND")
+	rest.RegisterError(user.ErrHoldNotActive, http.StatusConflict, "HOLD_NOT_ACTIVE")
+	rest.RegisterError(user.ErrCaptureExceedsHold, http.StatusUnprocessableEntity, "CAPTURE_EXCEEDS_HOLD")
+	rest.RegisterError(user.ErrHoldOwnedByBet, http.StatusConflict, "HOLD_OWNED_BY_BET")
+	rest.RegisterError(user.ErrAlreadyReversed, http.StatusConflict, "ALREADY_REVERSED")
+	rest.RegisterError(user.ErrNotReversible, http.StatusConflict, "NOT_REVERSIBLE")
+	rest.RegisterError(user.ErrReversalExceedsAmount, http.StatusUnprocessableEntity, "REVERSAL_EXCEEDS_AMOUNT")
+	rest.RegisterError(user.ErrBetNotFound, http.StatusNotFound, "BET_NOT_FOUND")
+	rest.RegisterError(user.ErrBetSettled, http.StatusConflict, "BET_SETTLED")
+	rest.RegisterError(user.ErrInvalidOutcome, http.StatusUnprocessableEntity, "INVALID_OUTCOME")
+	rest.RegisterError(user.ErrMissingSelection, http.StatusUnprocessableEntity, "MISSING_SELECTION")
+	rest.RegisterError(user.ErrCurrencyMismatch, http.StatusUnprocessableEntity, "CURRENCY_MISMATCH")
+	rest.RegisterError(user.ErrBonusActive, http.StatusConflict, "BONUS_ACTIVE")
+	rest.RegisterError(user.ErrInvalidProviderTxn, http.StatusUnprocessableEntity, "INVALID_PROVIDER_TRANSACTION")
+	rest.RegisterError(user.ErrProviderTxnConflict, http.StatusConflict, "PROVIDER_TRANSACTION_CONFLICT")
+	rest.RegisterError(ledger.ErrNotFound, http.StatusNotFound, "TRANSACTION_NOT_FOUND")
+	rest.RegisterError(fee.ErrInvalidSchedule, http.StatusBadRequest, "INVALID_FEE_SCHEDULE")
+	rest.RegisterError(importer.ErrInvalidFormat, http.StatusBadRequest, "INVALID_IMPORT_FORMAT")
+	rest.RegisterError(importer.ErrInvalidHeader, http.StatusBadRequest, "INVALID_IMPORT_HEADER")
+	rest.RegisterError(importer.ErrNotFound, http.StatusNotFound, "IMPORT_JOB_NOT_FOUND")
+	rest.RegisterError(limit.ErrLimitExceeded, http.StatusUnprocessableEntity, "LIMIT_EXCEEDED")
+	rest.RegisterError(reconcile.ErrNoReport, http.StatusNotFound, "NO_RECONCILIATION_REPORT")
+	rest.RegisterError(risk.ErrDenied, http.StatusForbidden, "RISK_DENIED")
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/fee"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Fee represents the fees API method handler set.
+type Fee struct {
+	MasterDB *db.DB
+}
+
+func (f *Fee) getFees(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	schedules, err := fee.List(ctx, f.MasterDB)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, schedules, http.StatusOK)
+	return nil
+}
+
+func (f *Fee) putFee(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var schedule fee.Schedule
+	err := rest.Unmarshal(r.Body, &schedule)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	err = fee.Set(ctx, f.MasterDB, schedule)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, true, http.StatusOK)
+	return nil
+}
+
+// getFeeQuote returns the fee for the op and amount query parameters before
+// the user commits to the transaction.
+func (f *Fee) getFeeQuote(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	op := r.URL.Query().Get("op")
+	if op != ledger.TypeWithdraw && op != ledger.TypeTransfer {
+		return rest.InvalidError{{Fld: "op", Err: "must be withdraw or transfer"}}
+	}
+
+	amount, err := queryInt(r, "amount", 0)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	quote, err := user.QuoteFeeByID(ctx, f.MasterDB, params["userID"], op, int64(amount))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, quote, http.StatusOK)
+	return nil
+}
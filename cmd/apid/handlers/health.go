@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/timurguseynov/go-wallet-api/internal/health"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+// Health represents the probes API method handler set.
+type Health struct {
+	Checker *health.Checker
+}
+
+// getHealthz is the liveness probe.
+func (h *Health) getHealthz(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	rest.Respond(ctx, w, h.Checker.Live(), http.StatusOK)
+	return nil
+}
+
+// getReadyz is the readiness probe, it fails with 503 and the checks that
+// didn't pass while the service shouldn't get traffic.
+func (h *Health) getReadyz(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	report := h.Checker.Ready(time.Now())
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	rest.Respond(ctx, w, report, status)
+	return nil
+}
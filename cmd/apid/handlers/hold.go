@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Hold represents the fund holds API method handler set.
+type Hold struct {
+	MasterDB *db.DB
+}
+
+// PostHold creates a hold. ExpiresIn is in seconds, the hold lasts
+// user.DefaultHoldTTL without it.
+type PostHold struct {
+	ID        string `json:"id"`
+	Amount    int64  `json:"amount"`
+	ExpiresIn int64  `json:"expires_in,omitempty"`
+	Reference string `json:"reference,omitempty"`
+}
+
+func (a PostHold) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostHold) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.ID, validation.Required),
+		validation.Field(&a.Amount, validation.Required),
+		validation.Field(&a.Amount, validation.Min(1)),
+		validation.Field(&a.ExpiresIn, validation.Min(0)),
+	}
+}
+
+// PostHoldCapture captures Amount of the hold, all of it when it's zero.
+type PostHoldCapture struct {
+	Amount int64 `json:"amount,omitempty"`
+}
+
+func (a PostHoldCapture) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostHoldCapture) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.Amount, validation.Min(0)),
+	}
+}
+
+func (h *Hold) postHold(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var holdCreate PostHold
+	err := rest.Unmarshal(r.Body, &holdCreate)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	ttl := time.Duration(holdCreate.ExpiresIn) * time.Second
+	hold, err := user.CreateHold(ctx, h.MasterDB, holdCreate.ID, holdCreate.Amount, ttl, holdCreate.Reference)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, hold, http.StatusOK)
+	return nil
+}
+
+func (h *Hold) getHold(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	hold, err := user.GetHoldByID(ctx, h.MasterDB, params["holdID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, hold, http.StatusOK)
+	return nil
+}
+
+func (h *Hold) postHoldCapture(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var holdCapture PostHoldCapture
+	err := rest.Unmarshal(r.Body, &holdCapture)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	hold, err := user.CaptureHold(ctx, h.MasterDB, params["holdID"], holdCapture.Amount)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, hold, http.StatusOK)
+	return nil
+}
+
+func (h *Hold) postHoldVoid(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	hold, err := user.VoidHold(ctx, h.MasterDB, params["holdID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, hold, http.StatusOK)
+	return nil
+}
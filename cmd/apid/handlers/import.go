@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/importer"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+// Import represents the bulk user import API method handler set.
+type Import struct {
+	MasterDB *db.DB
+}
+
+// contentFormats maps the content types of import files to their format.
+var contentFormats = map[string]string{
+	"text/csv":             importer.FormatCSV,
+	"application/x-ndjson": importer.FormatNDJSON,
+}
+
+// postImport starts importing the users in the request body, a CSV or
+// NDJSON file as given by the format query parameter or the content type.
+// Rows that fail validation are reported on the job rather than failing the
+// request. With dry_run=true nothing is committed.
+func (i *Import) postImport(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		format = contentFormats[ct]
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			return rest.InvalidError{{Fld: "dry_run", Err: "must be true or false"}}
+		}
+	}
+
+	rows, errs, err := importer.Parse(r.Body, format)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	job, err := importer.Start(ctx, i.MasterDB, rows, errs, dryRun)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, job, http.StatusAccepted)
+	return nil
+}
+
+// getImport returns the progress of an import job.
+func (i *Import) getImport(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	job, err := importer.GetJob(ctx, i.MasterDB, params["jobID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, job, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+// Ledger represents the ledger audit API method handler set.
+type Ledger struct {
+	MasterDB *db.DB
+}
+
+// getSnapshot returns the whole hash chained ledger with its checkpoints,
+// the input of cmd/ledgerverify.
+func (l *Ledger) getSnapshot(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	snapshot, err := ledger.TakeSnapshot(ctx, l.MasterDB)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, snapshot, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/limit"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Limit represents the transaction limits API method handler set.
+type Limit struct {
+	MasterDB *db.DB
+}
+
+func (l *Limit) getLimits(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	limits, err := limit.Get(ctx, l.MasterDB, params["userID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, limits, http.StatusOK)
+	return nil
+}
+
+func (l *Limit) putLimits(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var limits limit.Limits
+	err := rest.Unmarshal(r.Body, &limits)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	limits.ID = params["userID"]
+
+	if _, err := user.GetByID(ctx, l.MasterDB, limits.ID); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	err = limit.Set(ctx, l.MasterDB, limits)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, true, http.StatusOK)
+	return nil
+}
+
+func (l *Limit) deleteLimits(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	err := limit.Delete(ctx, l.MasterDB, params["userID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, nil, http.StatusNoContent)
+	return nil
+}
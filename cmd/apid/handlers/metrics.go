@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+// Metrics represents the metrics API method handler set.
+type Metrics struct {
+	Handler http.Handler
+}
+
+// getMetrics serves the metrics in the Prometheus text format.
+func (m *Metrics) getMetrics(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	// Set the status code for the request logger middleware.
+	v := ctx.Value(rest.KeyValues).(*rest.Values)
+	v.StatusCode = http.StatusOK
+
+	m.Handler.ServeHTTP(w, r)
+	return nil
+}
@@ -47,30 +47,35 @@ func (n *Notifier) leaderBoard(ctx context.Context, w http.ResponseWriter, r *ht
 	}
 }
 
+// outcomes streams bets as they're settled. Each message is the list of bets
+// settled since the previous one.
 func (n *Notifier) outcomes(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
 
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
-	var lastCheckUsers []user.User
+	// only stream what's settled after the client connected
+	lastSeq, err := user.LastSettleSeq(ctx, n.MasterDB)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			users, err := user.List(ctx, n.MasterDB)
+			bets, err := user.ListSettledSince(ctx, n.MasterDB, lastSeq)
 			if err != nil {
 				return errors.Wrap(err, "")
 			}
 
-			// only send data if it's changed
-			if reflect.DeepEqual(lastCheckUsers, users) {
+			if len(bets) == 0 {
 				continue
 			}
-			lastCheckUsers = users
+			lastSeq = bets[len(bets)-1].SettleSeq
 
-			err = rest.WebsocketRespond(ctx, users)
+			err = rest.WebsocketRespond(ctx, bets)
 			if err != nil {
 				return errors.Wrap(err, "")
 			}
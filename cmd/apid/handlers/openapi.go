@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/timurguseynov/go-wallet-api/internal/fee"
+	"github.com/timurguseynov/go-wallet-api/internal/health"
+	"github.com/timurguseynov/go-wallet-api/internal/importer"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/limit"
+	"github.com/timurguseynov/go-wallet-api/internal/reconcile"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/statement"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Title and version of the API in its OpenAPI spec.
+const (
+	SpecTitle   = "go-wallet-api"
+	SpecVersion = "1.0.0"
+)
+
+// OpenAPI serves the spec of the routes of App.
+type OpenAPI struct {
+	App *rest.App
+}
+
+func (o *OpenAPI) getOpenAPI(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	rest.Respond(ctx, w, o.App.OpenAPI(SpecTitle, SpecVersion), http.StatusOK)
+	return nil
+}
+
+// Parameters used by several routes.
+var (
+	pageParams = []rest.Param{
+		{Name: "limit", Type: "integer", Description: "Most results returned."},
+		{Name: "offset", Type: "integer", Description: "Results skipped."},
+	}
+	authParam = rest.Param{
+		Name:        rest.AuthorizationHeader,
+		In:          "header",
+		Description: "Bearer token of a principal with the admin role.",
+		Required:    true,
+	}
+	signatureParams = []rest.Param{
+		{
+			Name:        rest.SignatureHeader,
+			In:          "header",
+			Description: "Hex encoded HMAC-SHA256 of the timestamp, a dot and the body with the shared secret.",
+			Required:    true,
+		},
+		{
+			Name:        rest.SignatureTimestampHeader,
+			In:          "header",
+			Type:        "integer",
+			Description: "Unix time the request was signed at, it's rejected a few minutes later.",
+			Required:    true,
+		},
+	}
+)
+
+// init documents the token the admin routes require.
+func init() {
+	for k, d := range docs {
+		if strings.Contains(k, " /api/v1/admin/") {
+			d.Params = append([]rest.Param{authParam}, d.Params...)
+			docs[k] = d
+		}
+	}
+}
+
+// docs describes every route of API in the OpenAPI spec, a route missing
+// here fails the tests.
+var docs = map[string]rest.Doc{
+	"GET /metrics": {
+		Summary:     "Prometheus metrics",
+		Tag:         "operations",
+		ContentType: "text/plain",
+	},
+	"GET /healthz": {
+		Summary:  "Liveness probe",
+		Tag:      "operations",
+		Response: health.Report{},
+	},
+	"GET /readyz": {
+		Summary:  "Readiness probe, 503 when the service shouldn't get traffic",
+		Tag:      "operations",
+		Response: health.Report{},
+	},
+	"GET /openapi.json": {
+		Summary:  "This OpenAPI spec",
+		Tag:      "operations",
+		Response: map[string]interface{}{},
+	},
+
+	// user
+	"POST /api/v1/user/create": {
+		Summary:  "Create a user",
+		Tag:      "users",
+		Request:  user.User{},
+		Response: user.User{},
+	},
+	"GET /api/v1/user": {
+		Summary: "Search users",
+		Tag:     "users",
+		Params: append([]rest.Param{
+			{Name: "q", Description: "Text matching the name or email."},
+			{Name: "status", Description: "Status of the users."},
+		}, pageParams...),
+		Response: GetUsers{},
+	},
+	"GET /api/v1/user/email/{email}": {
+		Summary:  "Get a user by email",
+		Tag:      "users",
+		Response: user.User{},
+	},
+	"GET /api/v1/user/{userID}": {
+		Summary:  "Get a user",
+		Tag:      "users",
+		Response: user.User{},
+	},
+	"PUT /api/v1/admin/user/{userID}": {
+		Summary:  "Update a user",
+		Tag:      "users",
+		Request:  user.User{},
+		Response: user.User{},
+	},
+	"DELETE /api/v1/admin/user/{userID}": {
+		Summary: "Delete a user",
+		Tag:     "users",
+		Status:  http.StatusNoContent,
+	},
+	"PUT /api/v1/admin/user/{userID}/status": {
+		Summary:  "Change the status of a user",
+		Tag:      "users",
+		Request:  PutUserStatus{},
+		Response: true,
+	},
+
+	// wallet
+	"POST /api/v1/wallet/deposit": {
+		Summary:  "Deposit into a wallet",
+		Tag:      "wallet",
+		Request:  PostUserAmount{},
+		Response: ledger.Transaction{},
+	},
+	"POST /api/v1/wallet/withdraw": {
+		Summary:  "Withdraw from a wallet",
+		Tag:      "wallet",
+		Request:  PostUserAmount{},
+		Response: ledger.Transaction{},
+	},
+	"POST /api/v1/wallet/transfer": {
+		Summary:  "Transfer between wallets",
+		Tag:      "wallet",
+		Request:  PostUserTransfer{},
+		Response: ledger.Transaction{},
+	},
+	"GET /api/v1/wallet/balance/{userID}": {
+		Summary: "Get the balances of a wallet, or the cash balance at a past time",
+		Tag:     "wallet",
+		Params: []rest.Param{
+			{Name: "at", Description: "RFC 3339 time, responds with GetUserBalanceAt when set."},
+		},
+		Response: GetUserBalance{},
+	},
+	"GET /api/v1/wallet/balance/{userID}/range": {
+		Summary: "Get the movements of a wallet and its balances around a period",
+		Tag:     "wallet",
+		Params: []rest.Param{
+			{Name: "from", Description: "RFC 3339 start of the period.", Required: true},
+			{Name: "to", Description: "RFC 3339 end of the period, now by default."},
+		},
+		Response: ledger.Range{},
+	},
+	"POST /api/v1/wallet/batch": {
+		Summary:  "Apply deposits, withdrawals and transfers atomically",
+		Tag:      "wallet",
+		Request:  PostBatch{},
+		Response: PostBatchResponse{},
+	},
+	"GET /api/v1/wallet/statement/{userID}": {
+		Summary: "Download a statement",
+		Tag:     "wallet",
+		Params: []rest.Param{
+			{Name: "month", Description: "Month as YYYY-MM, instead of from and to."},
+			{Name: "from", Description: "RFC 3339 start of the period."},
+			{Name: "to", Description: "RFC 3339 end of the period."},
+			{Name: "format", Description: "json or csv."},
+		},
+		Response:    statement.Statement{},
+		ContentType: "text/csv",
+	},
+	"GET /api/v1/wallet/fee/{userID}": {
+		Summary: "Quote the fee of an operation",
+		Tag:     "wallet",
+		Params: []rest.Param{
+			{Name: "op", Description: "Operation charged.", Required: true},
+			{Name: "amount", Type: "integer", Description: "Amount of the operation."},
+		},
+		Response: fee.Quote{},
+	},
+
+	// holds
+	"POST /api/v1/wallet/hold": {
+		Summary:  "Reserve funds",
+		Tag:      "holds",
+		Request:  PostHold{},
+		Response: user.Hold{},
+	},
+	"GET /api/v1/wallet/hold/{holdID}": {
+		Summary:  "Get a hold",
+		Tag:      "holds",
+		Response: user.Hold{},
+	},
+	"POST /api/v1/wallet/hold/{holdID}/capture": {
+		Summary:  "Capture a hold",
+		Tag:      "holds",
+		Request:  PostHoldCapture{},
+		Response: user.Hold{},
+	},
+	"POST /api/v1/wallet/hold/{holdID}/void": {
+		Summary:  "Release a hold",
+		Tag:      "holds",
+		Response: user.Hold{},
+	},
+
+	// bets
+	"POST /api/v1/wallet/bet": {
+		Summary:  "Place a bet",
+		Tag:      "bets",
+		Request:  PostBet{},
+		Response: user.Bet{},
+	},
+	"GET /api/v1/wallet/bet/{betID}": {
+		Summary:  "Get a bet",
+		Tag:      "bets",
+		Response: user.Bet{},
+	},
+	"POST /api/v1/admin/bet/{betID}/settle": {
+		Summary:  "Settle a bet",
+		Tag:      "bets",
+		Request:  PostBetSettle{},
+		Response: user.Bet{},
+	},
+	"POST /api/v1/admin/market/{market}/settle": {
+		Summary:  "Settle the bets of a market",
+		Tag:      "bets",
+		Request:  PostMarketSettle{},
+		Response: []user.Bet{},
+	},
+
+	// bonuses
+	"POST /api/v1/admin/bonus": {
+		Summary:  "Grant a bonus",
+		Tag:      "bonuses",
+		Request:  PostBonus{},
+		Response: user.Bonus{},
+	},
+	"GET /api/v1/wallet/bonus/{userID}": {
+		Summary:  "List the bonuses of a user",
+		Tag:      "bonuses",
+		Response: []user.Bonus{},
+	},
+
+	// transactions
+	"GET /api/v1/wallet/transactions/{userID}": {
+		Summary:  "List the transactions of a user",
+		Tag:      "transactions",
+		Response: []ledger.Transaction{},
+	},
+	"GET /api/v1/wallet/transaction/{transactionID}": {
+		Summary:  "Get a transaction",
+		Tag:      "transactions",
+		Response: ledger.Transaction{},
+	},
+	"POST /api/v1/admin/transaction/{transactionID}/reverse": {
+		Summary:  "Reverse a deposit, withdrawal or transfer, in full or in part",
+		Tag:      "transactions",
+		Request:  PostTransactionReverse{},
+		Response: ledger.Transaction{},
+	},
+
+	// admin
+	"GET /api/v1/admin/ledger/snapshot": {
+		Summary:  "Take a snapshot of the ledger",
+		Tag:      "admin",
+		Response: ledger.Snapshot{},
+	},
+	"GET /api/v1/admin/audit": {
+		Summary: "Search the audit log",
+		Tag:     "admin",
+		Params: append([]rest.Param{
+			{Name: "actor", Description: "Who made the requests."},
+			{Name: "method", Description: "HTTP method of the requests."},
+			{Name: "route", Description: "Route template of the requests."},
+			{Name: "since", Description: "RFC 3339 time of the oldest requests."},
+			{Name: "until", Description: "RFC 3339 time the requests were made before."},
+		}, pageParams...),
+		Response: GetAudit{},
+	},
+	"GET /api/v1/admin/limits/{userID}": {
+		Summary:  "Get the limits of a user",
+		Tag:      "admin",
+		Response: limit.Limits{},
+	},
+	"PUT /api/v1/admin/limits/{userID}": {
+		Summary:  "Set the limits of a user, -1 lifts a global one",
+		Tag:      "admin",
+		Request:  limit.Limits{},
+		Response: true,
+	},
+	"DELETE /api/v1/admin/limits/{userID}": {
+		Summary: "Clear the limits of a user, the global ones apply again",
+		Tag:     "admin",
+		Status:  http.StatusNoContent,
+	},
+	"GET /api/v1/admin/fees": {
+		Summary:  "List the fee schedules",
+		Tag:      "admin",
+		Response: []fee.Schedule{},
+	},
+	"PUT /api/v1/admin/fees": {
+		Summary:  "Set a fee schedule",
+		Tag:      "admin",
+		Request:  fee.Schedule{},
+		Response: true,
+	},
+	"POST /api/v1/admin/import": {
+		Summary: "Start importing users with opening balances",
+		Tag:     "admin",
+		Params: []rest.Param{
+			{Name: "format", Description: "csv or ndjson, taken from the content type when missing."},
+			{Name: "dry_run", Type: "boolean", Description: "Check the file without importing it."},
+		},
+		Consumes: []string{"text/csv", "application/x-ndjson"},
+		Response: importer.Job{},
+		Status:   http.StatusAccepted,
+	},
+	"GET /api/v1/admin/import/{jobID}": {
+		Summary:  "Get an import job",
+		Tag:      "admin",
+		Response: importer.Job{},
+	},
+	"GET /api/v1/admin/reconciliation": {
+		Summary:  "Get the last reconciliation report",
+		Tag:      "admin",
+		Response: reconcile.Report{},
+	},
+	"POST /api/v1/admin/reconciliation": {
+		Summary:  "Reconcile the books now",
+		Tag:      "admin",
+		Response: reconcile.Report{},
+	},
+
+	// seamless wallet
+	"POST /api/v1/seamless/balance": {
+		Summary:  "Get the balance of a player",
+		Tag:      "seamless",
+		Params:   signatureParams,
+		Request:  PostSeamlessBalance{},
+		Response: GetSeamlessBalance{},
+	},
+	"POST /api/v1/seamless/debit": {
+		Summary:  "Debit the stake of a round",
+		Tag:      "seamless",
+		Params:   signatureParams,
+		Request:  PostSeamlessTxn{},
+		Response: user.ProviderTxn{},
+	},
+	"POST /api/v1/seamless/credit": {
+		Summary:  "Credit the winnings of a round",
+		Tag:      "seamless",
+		Params:   signatureParams,
+		Request:  PostSeamlessTxn{},
+		Response: user.ProviderTxn{},
+	},
+	"POST /api/v1/seamless/rollback": {
+		Summary:  "Roll a debit back",
+		Tag:      "seamless",
+		Params:   signatureParams,
+		Request:  PostSeamlessRollback{},
+		Response: user.ProviderTxn{},
+	},
+
+	// notifier
+	"GET /ws/topic/leaderboard": {
+		Summary:  "Websocket pushing the leaders as they change",
+		Tag:      "notifier",
+		Response: []user.User{},
+		Status:   http.StatusSwitchingProtocols,
+	},
+	"GET /ws/topic/outcomes": {
+		Summary:  "Websocket pushing the bets as they're settled",
+		Tag:      "notifier",
+		Response: []user.Bet{},
+		Status:   http.StatusSwitchingProtocols,
+	},
+}
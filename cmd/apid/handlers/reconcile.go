@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/reconcile"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+// Reconciliation represents the ledger reconciliation API method handler set.
+type Reconciliation struct {
+	MasterDB *db.DB
+}
+
+func (rc *Reconciliation) getReconciliation(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	report, err := reconcile.Last(ctx, rc.MasterDB)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, report, http.StatusOK)
+	return nil
+}
+
+func (rc *Reconciliation) postReconciliation(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	report, err := reconcile.Run(ctx, rc.MasterDB, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, report, http.StatusOK)
+	return nil
+}
@@ -6,26 +6,166 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/timurguseynov/go-wallet-api/config"
+	"github.com/timurguseynov/go-wallet-api/internal/audit"
 	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/health"
+	"github.com/timurguseynov/go-wallet-api/internal/metrics"
 	"github.com/timurguseynov/go-wallet-api/internal/rest"
 )
 
-// API returns a handler for a set of routes.
-func API(db *db.DB) http.Handler {
+// API returns a handler for a set of routes. hc backs the health probes.
+// The admin routes are only served to principals of tokens with the admin
+// role.
+func API(db *db.DB, conf config.Config, tokens rest.Tokens, hc *health.Checker) http.Handler {
 	// Create the web handler for setting routes and middleware.
-	app := rest.New(rest.RequestLoggerMiddleware, rest.ErrorHandlerMiddleware)
+	// Requests are audited and measured once errors are turned into
+	// responses, so the audit log and the metrics have the status the
+	// client got.
+	app := rest.New(rest.MetricsMiddleware, rest.RequestLoggerMiddleware, audit.Middleware(db), rest.ErrorHandlerMiddleware)
+
+	// metrics
+	m := Metrics{
+		Handler: metrics.Handler(),
+	}
+	app.Handle(http.MethodGet, "/metrics", m.getMetrics)
+
+	// health
+	hh := Health{
+		Checker: hc,
+	}
+	app.Handle(http.MethodGet, "/healthz", hh.getHealthz)
+	app.Handle(http.MethodGet, "/readyz", hh.getReadyz)
+
+	// spec
+	o := OpenAPI{
+		App: app,
+	}
+	app.Handle(http.MethodGet, "/openapi.json", o.getOpenAPI)
 
 	// Initialize the routes for the API binding the route to the
-	// handler code for each specified verb.
+	// handler code for each specified verb. They're versioned, the paths
+	// without the version are served until they're retired.
+	v1 := app.Group("/api/v1").Alias("/api", rest.Deprecation{
+		Since:  conf.Legacy.Deprecated,
+		Sunset: conf.Legacy.Sunset,
+	})
+	wallet := v1.Group("/wallet")
+	admin := v1.Group("/admin", rest.TokenMiddleware(tokens, rest.RoleAdmin))
 
 	// user
 	u := User{
 		MasterDB: db,
 	}
-	app.Handle(http.MethodPost, "/api/user/create", u.postUserCreate)
-	app.Handle(http.MethodPost, "/api/wallet/deposit", u.postUserDeposit)
-	app.Handle(http.MethodPost, "/api/wallet/withdraw", u.postUserWithdraw)
-	app.Handle(http.MethodGet, "/api/wallet/balance/{userID}", u.getUserBalance)
+	v1.Handle(http.MethodPost, "/user/create", u.postUserCreate)
+	v1.Handle(http.MethodGet, "/user", u.getUsers)
+	v1.Handle(http.MethodGet, "/user/email/{email}", u.getUserByEmail)
+	v1.Handle(http.MethodGet, "/user/{userID}", u.getUser)
+	wallet.Handle(http.MethodPost, "/deposit", u.postUserDeposit)
+	wallet.Handle(http.MethodPost, "/withdraw", u.postUserWithdraw)
+	wallet.Handle(http.MethodPost, "/transfer", u.postUserTransfer)
+	wallet.Handle(http.MethodGet, "/balance/{userID}", u.getUserBalance)
+	wallet.Handle(http.MethodGet, "/balance/{userID}/range", u.getUserBalanceRange)
+	admin.Handle(http.MethodPut, "/user/{userID}", u.putUser)
+	admin.Handle(http.MethodDelete, "/user/{userID}", u.deleteUser)
+	admin.Handle(http.MethodPut, "/user/{userID}/status", u.putUserStatus)
+
+	// batches
+	bt := Batch{
+		MasterDB: db,
+	}
+	wallet.Handle(http.MethodPost, "/batch", bt.postBatch)
+
+	// holds
+	h := Hold{
+		MasterDB: db,
+	}
+	wallet.Handle(http.MethodPost, "/hold", h.postHold)
+	wallet.Handle(http.MethodGet, "/hold/{holdID}", h.getHold)
+	wallet.Handle(http.MethodPost, "/hold/{holdID}/capture", h.postHoldCapture)
+	wallet.Handle(http.MethodPost, "/hold/{holdID}/void", h.postHoldVoid)
+
+	// bets
+	b := Bet{
+		MasterDB: db,
+	}
+	wallet.Handle(http.MethodPost, "/bet", b.postBet)
+	wallet.Handle(http.MethodGet, "/bet/{betID}", b.getBet)
+	admin.Handle(http.MethodPost, "/bet/{betID}/settle", b.postBetSettle)
+	admin.Handle(http.MethodPost, "/market/{market}/settle", b.postMarketSettle)
+
+	// bonuses
+	bn := Bonus{
+		MasterDB: db,
+	}
+	admin.Handle(http.MethodPost, "/bonus", bn.postBonus)
+	wallet.Handle(http.MethodGet, "/bonus/{userID}", bn.getUserBonuses)
+
+	// transactions
+	t := Transaction{
+		MasterDB: db,
+	}
+	wallet.Handle(http.MethodGet, "/transactions/{userID}", t.getUserTransactions)
+	wallet.Handle(http.MethodGet, "/transaction/{transactionID}", t.getTransaction)
+	admin.Handle(http.MethodPost, "/transaction/{transactionID}/reverse", t.postTransactionReverse)
+
+	// ledger
+	lg := Ledger{
+		MasterDB: db,
+	}
+	admin.Handle(http.MethodGet, "/ledger/snapshot", lg.getSnapshot)
+
+	// audit
+	au := Audit{
+		MasterDB: db,
+	}
+	admin.Handle(http.MethodGet, "/audit", au.getAudit)
+
+	// limits
+	l := Limit{
+		MasterDB: db,
+	}
+	admin.Handle(http.MethodGet, "/limits/{userID}", l.getLimits)
+	admin.Handle(http.MethodPut, "/limits/{userID}", l.putLimits)
+	admin.Handle(http.MethodDelete, "/limits/{userID}", l.deleteLimits)
+
+	// seamless wallet for game providers, every request is signed
+	sw := Seamless{
+		MasterDB: db,
+	}
+	sg := v1.Group("/seamless", rest.SignatureMiddleware(conf.Seamless.Secret, conf.Seamless.SignatureWindow))
+	sg.Handle(http.MethodPost, "/balance", sw.postBalance)
+	sg.Handle(http.MethodPost, "/debit", sw.postDebit)
+	sg.Handle(http.MethodPost, "/credit", sw.postCredit)
+	sg.Handle(http.MethodPost, "/rollback", sw.postRollback)
+
+	// fees
+	fe := Fee{
+		MasterDB: db,
+	}
+	wallet.Handle(http.MethodGet, "/fee/{userID}", fe.getFeeQuote)
+	admin.Handle(http.MethodGet, "/fees", fe.getFees)
+	admin.Handle(http.MethodPut, "/fees", fe.putFee)
+
+	// imports
+	im := Import{
+		MasterDB: db,
+	}
+	admin.Handle(http.MethodPost, "/import", im.postImport)
+	admin.Handle(http.MethodGet, "/import/{jobID}", im.getImport)
+
+	// statements
+	st := Statement{
+		MasterDB: db,
+	}
+	wallet.Handle(http.MethodGet, "/statement/{userID}", st.getStatement)
+
+	// reconciliation
+	rc := Reconciliation{
+		MasterDB: db,
+	}
+	admin.Handle(http.MethodGet, "/reconciliation", rc.getReconciliation)
+	admin.Handle(http.MethodPost, "/reconciliation", rc.postReconciliation)
 
 	// notifier
 	n := Notifier{
@@ -34,5 +174,7 @@ func API(db *db.DB) http.Handler {
 	app.WebsocketHandle("/ws/topic/leaderboard", n.leaderBoard)
 	app.WebsocketHandle("/ws/topic/outcomes", n.outcomes)
 
+	app.Describe(docs)
+
 	return app
 }
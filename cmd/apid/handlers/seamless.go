@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Seamless represents the seamless wallet API game providers call.
+type Seamless struct {
+	MasterDB *db.DB
+}
+
+type PostSeamlessBalance struct {
+	UserID string `json:"user_id"`
+}
+
+func (a PostSeamlessBalance) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostSeamlessBalance) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.UserID, validation.Required),
+	}
+}
+
+type GetSeamlessBalance struct {
+	UserID  string `json:"user_id"`
+	Balance int64  `json:"balance"`
+}
+
+// PostSeamlessTxn is a debit or a credit of a game round.
+type PostSeamlessTxn struct {
+	Provider      string `json:"provider"`
+	TransactionID string `json:"transaction_id"`
+	RoundID       string `json:"round_id"`
+	UserID        string `json:"user_id"`
+	Amount        int64  `json:"amount"`
+}
+
+func (a PostSeamlessTxn) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostSeamlessTxn) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.Provider, validation.Required),
+		validation.Field(&a.TransactionID, validation.Required),
+		validation.Field(&a.RoundID, validation.Required),
+		validation.Field(&a.UserID, validation.Required),
+		validation.Field(&a.Amount, validation.Required),
+		validation.Field(&a.Amount, validation.Min(1)),
+	}
+}
+
+// PostSeamlessRollback cancels the debit with RollbackOf as its transaction
+// id.
+type PostSeamlessRollback struct {
+	Provider      string `json:"provider"`
+	TransactionID string `json:"transaction_id"`
+	RoundID       string `json:"round_id"`
+	UserID        string `json:"user_id"`
+	RollbackOf    string `json:"rollback_of"`
+}
+
+func (a PostSeamlessRollback) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostSeamlessRollback) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.Provider, validation.Required),
+		validation.Field(&a.TransactionID, validation.Required),
+		validation.Field(&a.RoundID, validation.Required),
+		validation.Field(&a.UserID, validation.Required),
+		validation.Field(&a.RollbackOf, validation.Required),
+	}
+}
+
+func (s *Seamless) postBalance(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var balance PostSeamlessBalance
+	err := rest.Unmarshal(r.Body, &balance)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	u, err := user.GetByID(ctx, s.MasterDB, balance.UserID)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, GetSeamlessBalance{UserID: u.ID, Balance: u.Available()}, http.StatusOK)
+	return nil
+}
+
+func (s *Seamless) postDebit(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	return s.process(ctx, w, r, user.ProviderDebit)
+}
+
+func (s *Seamless) postCredit(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	return s.process(ctx, w, r, user.ProviderCredit)
+}
+
+func (s *Seamless) process(ctx context.Context, w http.ResponseWriter, r *http.Request, typ string) error {
+	var seamlessTxn PostSeamlessTxn
+	err := rest.Unmarshal(r.Body, &seamlessTxn)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	pt, err := user.ProcessProviderTxn(ctx, s.MasterDB, user.ProviderTxn{
+		Provider:      seamlessTxn.Provider,
+		TransactionID: seamlessTxn.TransactionID,
+		RoundID:       seamlessTxn.RoundID,
+		UserID:        seamlessTxn.UserID,
+		Type:          typ,
+		Amount:        seamlessTxn.Amount,
+	})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, pt, http.StatusOK)
+	return nil
+}
+
+func (s *Seamless) postRollback(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var rollback PostSeamlessRollback
+	err := rest.Unmarshal(r.Body, &rollback)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	pt, err := user.ProcessProviderTxn(ctx, s.MasterDB, user.ProviderTxn{
+		Provider:      rollback.Provider,
+		TransactionID: rollback.TransactionID,
+		RoundID:       rollback.RoundID,
+		UserID:        rollback.UserID,
+		Type:          user.ProviderRollback,
+		RollbackOf:    rollback.RollbackOf,
+	})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, pt, http.StatusOK)
+	return nil
+}
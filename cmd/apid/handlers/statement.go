@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/statement"
+)
+
+// Statement represents the account statement API method handler set.
+type Statement struct {
+	MasterDB *db.DB
+}
+
+// getStatement returns the user's statement for the month query parameter,
+// YYYY-MM in UTC, or for the period between the from and to RFC 3339
+// times. format is json, the default, or csv for a download.
+func (s *Statement) getStatement(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	from, to, err := statementPeriod(r)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		return rest.InvalidError{{Fld: "format", Err: "must be json or csv"}}
+	}
+
+	st, err := statement.Generate(ctx, s.MasterDB, params["userID"], from, to)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if format != "csv" {
+		rest.Respond(ctx, w, st, http.StatusOK)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := statement.WriteCSV(&buf, *st); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s.csv", st.UserID, from.UTC().Format("2006-01-02"))
+	rest.RespondFile(ctx, w, buf.Bytes(), "text/csv", filename, http.StatusOK)
+	return nil
+}
+
+// statementPeriod reads the period of a statement from the month or the
+// from and to query parameters.
+func statementPeriod(r *http.Request) (time.Time, time.Time, error) {
+	if month := r.URL.Query().Get("month"); month != "" {
+		t, err := time.Parse("2006-01", month)
+		if err != nil {
+			return time.Time{}, time.Time{}, rest.InvalidError{{Fld: "month", Err: "must be YYYY-MM"}}
+		}
+
+		from, to := statement.Month(t)
+		return from, to, nil
+	}
+
+	from, err := queryTime(r, "from")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := queryTime(r, "to")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if from.IsZero() {
+		return time.Time{}, time.Time{}, rest.InvalidError{{Fld: "month", Err: "cannot be blank without from"}}
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, rest.InvalidError{{Fld: "to", Err: "must be after from"}}
+	}
+
+	return from, to, nil
+}
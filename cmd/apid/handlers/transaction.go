@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Transaction represents the ledger transactions API method handler set.
+type Transaction struct {
+	MasterDB *db.DB
+}
+
+// PostTransactionReverse reverses Amount of a transaction, all of it when
+// it's zero.
+type PostTransactionReverse struct {
+	Amount int64 `json:"amount,omitempty"`
+}
+
+func (a PostTransactionReverse) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostTransactionReverse) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.Amount, validation.Min(0)),
+	}
+}
+
+func (t *Transaction) getTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	tx, err := user.GetTransactionByID(ctx, t.MasterDB, params["transactionID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, tx, http.StatusOK)
+	return nil
+}
+
+func (t *Transaction) getUserTransactions(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ts, err := user.ListTransactionsByID(ctx, t.MasterDB, params["userID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if ts == nil {
+		ts = []ledger.Transaction{}
+	}
+
+	rest.Respond(ctx, w, ts, http.StatusOK)
+	return nil
+}
+
+func (t *Transaction) postTransactionReverse(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var reverse PostTransactionReverse
+	err := rest.Unmarshal(r.Body, &reverse)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	tx, err := user.ReverseTransaction(ctx, t.MasterDB, params["transactionID"], reverse.Amount)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, tx, http.StatusOK)
+	return nil
+}
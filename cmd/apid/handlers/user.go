@@ -3,6 +3,8 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"strconv"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/timurguseynov/go-wallet-api/internal/rest"
@@ -24,10 +26,69 @@ type PostUserAmount struct {
 }
 
 func (a PostUserAmount) Validate() error {
-	return validation.ValidateStruct(&a,
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostUserAmount) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
 		validation.Field(&a.Amount, validation.Required),
-		validation.Field(&a.Amount, validation.Min(10)),
-	)
+		validation.Field(&a.Amount, validation.Min(1)),
+	}
+}
+
+type PostUserTransfer struct {
+	ID     string `json:"id"`
+	To     string `json:"to"`
+	Amount int64  `json:"amount"`
+}
+
+func (a PostUserTransfer) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PostUserTransfer) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.To, validation.Required),
+		validation.Field(&a.Amount, validation.Required),
+		validation.Field(&a.Amount, validation.Min(1)),
+	}
+}
+
+type PutUserStatus struct {
+	Status string `json:"status"`
+}
+
+func (a PutUserStatus) Validate() error {
+	return validation.ValidateStruct(&a, a.Rules()...)
+}
+
+func (a *PutUserStatus) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&a.Status, validation.Required),
+		validation.Field(&a.Status, validation.In(user.StatusActive, user.StatusFrozen, user.StatusSuspended, user.StatusClosed)),
+	}
+}
+
+// GetUserBalance shows the total cash balance and how much of it is
+// available, that is not reserved by holds, next to the bonus balance.
+type GetUserBalance struct {
+	Balance   int64 `json:"balance"`
+	Available int64 `json:"available"`
+	Held      int64 `json:"held"`
+	Bonus     int64 `json:"bonus"`
+}
+
+// GetUserBalanceAt shows the cash balance as it was at a past moment.
+type GetUserBalanceAt struct {
+	Balance int64     `json:"balance"`
+	At      time.Time `json:"at"`
+}
+
+type GetUsers struct {
+	Users  []user.User `json:"users"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
 }
 
 func (u *User) postUserCreate(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
@@ -42,14 +103,96 @@ func (u *User) postUserCreate(ctx context.Context, w http.ResponseWriter, r *htt
 		return errors.Wrap(err, "")
 	}
 
-	resp := user.User{
-		ID: id,
+	resp, err := user.GetByID(ctx, u.MasterDB, id)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, resp, http.StatusOK)
+	return nil
+}
+
+func (u *User) getUser(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	usr, err := user.GetByID(ctx, u.MasterDB, params["userID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, usr, http.StatusOK)
+	return nil
+}
+
+func (u *User) getUserByEmail(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	usr, err := user.GetByEmail(ctx, u.MasterDB, params["email"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, usr, http.StatusOK)
+	return nil
+}
+
+func (u *User) getUsers(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	pageLimit, err := queryInt(r, "limit", 20)
+	if err != nil {
+		return err
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		return err
+	}
+	if pageLimit < 1 || pageLimit > 100 {
+		return rest.InvalidError{{Fld: "limit", Err: "must be between 1 and 100"}}
+	}
+
+	q := user.Query{
+		Text:   r.URL.Query().Get("q"),
+		Status: r.URL.Query().Get("status"),
+		Limit:  pageLimit,
+		Offset: offset,
+	}
+	users, total, err := user.Search(ctx, u.MasterDB, q)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	resp := GetUsers{
+		Users:  users,
+		Total:  total,
+		Limit:  pageLimit,
+		Offset: offset,
 	}
 
 	rest.Respond(ctx, w, resp, http.StatusOK)
 	return nil
 }
 
+func (u *User) putUser(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var userUpdate user.User
+	err := rest.Unmarshal(r.Body, &userUpdate)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	usr, err := user.UpdateByID(ctx, u.MasterDB, params["userID"], userUpdate)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, usr, http.StatusOK)
+	return nil
+}
+
+func (u *User) deleteUser(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	err := user.DeleteByID(ctx, u.MasterDB, params["userID"])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, nil, http.StatusNoContent)
+	return nil
+}
+
 func (u *User) postUserDeposit(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
 	var userAmount PostUserAmount
 	err := rest.Unmarshal(r.Body, &userAmount)
@@ -57,12 +200,14 @@ func (u *User) postUserDeposit(ctx context.Context, w http.ResponseWriter, r *ht
 		return errors.Wrap(err, "")
 	}
 
-	err = user.DepositByID(ctx, u.MasterDB, userAmount.ID, userAmount.Amount)
+	rest.SetUserID(ctx, userAmount.ID)
+
+	t, err := user.DepositByID(ctx, u.MasterDB, userAmount.ID, userAmount.Amount)
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
 
-	rest.Respond(ctx, w, true, http.StatusOK)
+	rest.Respond(ctx, w, t, http.StatusOK)
 	return nil
 }
 
@@ -73,28 +218,126 @@ func (u *User) postUserWithdraw(ctx context.Context, w http.ResponseWriter, r *h
 		return errors.Wrap(err, "")
 	}
 
-	err = user.WithdrawByID(ctx, u.MasterDB, userAmount.ID, userAmount.Amount)
+	rest.SetUserID(ctx, userAmount.ID)
+
+	t, err := user.WithdrawByID(ctx, u.MasterDB, userAmount.ID, userAmount.Amount)
 	if err != nil {
-		if err == user.ErrInsufficientFunds {
-			return rest.NewResponseError(err, http.StatusPaymentRequired)
-		}
 		return errors.Wrap(err, "")
 	}
 
-	rest.Respond(ctx, w, true, http.StatusOK)
+	rest.Respond(ctx, w, t, http.StatusOK)
+	return nil
+}
+
+func (u *User) postUserTransfer(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var userTransfer PostUserTransfer
+	err := rest.Unmarshal(r.Body, &userTransfer)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.SetUserID(ctx, userTransfer.ID)
+
+	t, err := user.TransferByID(ctx, u.MasterDB, userTransfer.ID, userTransfer.To, userTransfer.Amount)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, t, http.StatusOK)
 	return nil
 }
 
+// getUserBalance returns the current balances, or only the cash balance as
+// it was at the RFC 3339 time given by the at query parameter.
 func (u *User) getUserBalance(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	userBalance, err := user.GetBalanceByID(ctx, u.MasterDB, params["userID"])
+	at, err := queryTime(r, "at")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if !at.IsZero() {
+		balance, err := user.GetBalanceAtByID(ctx, u.MasterDB, params["userID"], at)
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		rest.Respond(ctx, w, GetUserBalanceAt{Balance: balance, At: at}, http.StatusOK)
+		return nil
+	}
+
+	usr, err := user.GetByID(ctx, u.MasterDB, params["userID"])
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
 
-	b := user.User{
-		Balance: userBalance,
+	b := GetUserBalance{
+		Balance:   usr.Balance,
+		Available: usr.Available(),
+		Held:      usr.Held,
+		Bonus:     usr.Bonus,
 	}
 
 	rest.Respond(ctx, w, b, http.StatusOK)
 	return nil
 }
+
+// getUserBalanceRange returns the opening and closing cash balances for the
+// period between the from and to query parameters, to exclusive, and the
+// movements in between. to defaults to now.
+func (u *User) getUserBalanceRange(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	from, err := queryTime(r, "from")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	to, err := queryTime(r, "to")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if from.IsZero() {
+		return rest.InvalidError{{Fld: "from", Err: "cannot be blank"}}
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if !from.Before(to) {
+		return rest.InvalidError{{Fld: "to", Err: "must be after from"}}
+	}
+
+	rng, err := user.GetBalanceRangeByID(ctx, u.MasterDB, params["userID"], from, to)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, rng, http.StatusOK)
+	return nil
+}
+
+func (u *User) putUserStatus(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	var userStatus PutUserStatus
+	err := rest.Unmarshal(r.Body, &userStatus)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	err = user.SetStatusByID(ctx, u.MasterDB, params["userID"], userStatus.Status)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rest.Respond(ctx, w, true, http.StatusOK)
+	return nil
+}
+
+// queryInt reads the query parameter name as an int, def if it's missing.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, rest.InvalidError{{Fld: name, Err: "must be a positive number"}}
+	}
+
+	return i, nil
+}
@@ -7,7 +7,9 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/ed25519"
+	"encoding/base64"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,49 +17,217 @@ import (
 	"time"
 
 	"github.com/timurguseynov/go-wallet-api/config"
+	"github.com/timurguseynov/go-wallet-api/internal/audit"
 	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/health"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/limit"
+	"github.com/timurguseynov/go-wallet-api/internal/reconcile"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/risk"
+	"github.com/timurguseynov/go-wallet-api/internal/tracing"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
 
 	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
 )
 
-// init is called before main. We are using init to customize logging output.
-func init() {
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
-	log.SetOutput(os.Stdout)
-}
-
 // main is the entry point for the application.
 func main() {
-	log.Println("main : Started")
-
 	// Read the config from .env file or environment variables
 	conf, err := config.Read()
 	if err != nil {
-		log.Fatal("main : couldn't read config", err)
+		fatal("couldn't read config", err)
+	}
+
+	// Log structured lines from here on, the log package included.
+	logger, err := rest.NewLogger(os.Stdout, conf.Log.Level, conf.Log.Format)
+	if err != nil {
+		fatal("couldn't create logger", err)
+	}
+	slog.SetDefault(logger)
+	rest.SetLogger(logger)
+
+	slog.Info("main : Started")
+
+	// Trace requests, continuing the traces callers send.
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter: conf.Trace.Exporter,
+		Endpoint: conf.Trace.Endpoint,
+		Insecure: conf.Trace.Insecure,
+		File:     conf.Trace.File,
+	})
+	if err != nil {
+		fatal("couldn't set up tracing", err)
+	}
+
+	// Admins authenticate with the bearer tokens configured for them.
+	tokens, err := rest.ParseTokens(conf.Auth.Tokens)
+	if err != nil {
+		fatal("couldn't read auth tokens", err)
 	}
 
 	// Register the Master Session for the database.
-	log.Println("main : Started : Capturing Master DB:")
 	dbConn, err := db.NewDB()
 	if err != nil {
-		log.Fatal("main : couldn't connect to database", err)
-	} else {
-		log.Println("main : DB captured successfully")
+		fatal("couldn't connect to database", err)
+	}
+	slog.Info("main : DB captured successfully")
+
+	// Readiness waits for the startup below to finish and for the background
+	// jobs to keep running.
+	hc := health.New(dbConn)
+
+	// Set the limits every user is held to unless they have overrides.
+	err = limit.Set(context.Background(), dbConn, limit.Limits{
+		ID:        limit.Global,
+		MinAmount: conf.Limits.MinAmount,
+		MaxAmount: conf.Limits.MaxAmount,
+		Daily: limit.Window{
+			Deposit:  conf.Limits.DailyDeposit,
+			Withdraw: conf.Limits.DailyWithdraw,
+			Count:    conf.Limits.DailyCount,
+		},
+		Weekly: limit.Window{
+			Deposit:  conf.Limits.WeeklyDeposit,
+			Withdraw: conf.Limits.WeeklyWithdraw,
+			Count:    conf.Limits.WeeklyCount,
+		},
+		Monthly: limit.Window{
+			Deposit:  conf.Limits.MonthlyDeposit,
+			Withdraw: conf.Limits.MonthlyWithdraw,
+			Count:    conf.Limits.MonthlyCount,
+		},
+	})
+	if err != nil {
+		fatal("couldn't set global limits", err)
+	}
+
+	// Load the risk rules and keep them in sync with the file so they can be
+	// changed without a restart.
+	if conf.Risk.Rules != "" {
+		if err := risk.Load(context.Background(), dbConn, conf.Risk.Rules); err != nil {
+			fatal("couldn't load risk rules", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go risk.Watch(ctx, dbConn, conf.Risk.Rules, conf.Risk.ReloadInterval)
+	}
+
+	// Release holds once they expire.
+	go func() {
+		ticker := time.NewTicker(conf.Holds.ExpireInterval)
+		defer ticker.Stop()
+
+		hc.Beat("holds", conf.Holds.ExpireInterval)
+		for range ticker.C {
+			hc.Beat("holds", conf.Holds.ExpireInterval)
+			n, err := user.ExpireHolds(context.Background(), dbConn, time.Now())
+			if err != nil {
+				slog.Error("couldn't expire holds", "job", "holds", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("expired holds", "job", "holds", "count", n)
+			}
+		}
+	}()
+
+	// Forfeit bonuses that weren't wagered in time.
+	go func() {
+		ticker := time.NewTicker(conf.Bonus.ExpireInterval)
+		defer ticker.Stop()
+
+		hc.Beat("bonuses", conf.Bonus.ExpireInterval)
+		for range ticker.C {
+			hc.Beat("bonuses", conf.Bonus.ExpireInterval)
+			n, err := user.ExpireBonuses(context.Background(), dbConn, time.Now())
+			if err != nil {
+				slog.Error("couldn't expire bonuses", "job", "bonuses", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("expired bonuses", "job", "bonuses", "count", n)
+			}
+		}
+	}()
+
+	// Sign the head of the ledger on a schedule, always with the configured
+	// key so the checkpoints can be verified after a restart.
+	key, err := ledger.ParseSigningKey(conf.Ledger.SigningKey)
+	if err != nil {
+		fatal("couldn't read ledger signing key", err)
 	}
+	slog.Info("main : ledger checkpoints public key", "public_key", base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)))
+
+	go func() {
+		ticker := time.NewTicker(conf.Ledger.CheckpointInterval)
+		defer ticker.Stop()
+
+		hc.Beat("checkpoints", conf.Ledger.CheckpointInterval)
+		for range ticker.C {
+			hc.Beat("checkpoints", conf.Ledger.CheckpointInterval)
+			if _, err := ledger.CreateCheckpoint(context.Background(), dbConn, key, time.Now()); err != nil {
+				slog.Error("couldn't create checkpoint", "job", "checkpoints", "error", err)
+			}
+		}
+	}()
+
+	// Check the books against the balances on a schedule, the last report is
+	// served on the admin API.
+	go func() {
+		ticker := time.NewTicker(conf.Reconcile.Interval)
+		defer ticker.Stop()
+
+		hc.Beat("reconcile", conf.Reconcile.Interval)
+		for range ticker.C {
+			hc.Beat("reconcile", conf.Reconcile.Interval)
+			r, err := reconcile.Run(context.Background(), dbConn, time.Now())
+			if err != nil {
+				slog.Error("couldn't reconcile", "job", "reconcile", "error", err)
+				continue
+			}
+			if !r.Balanced {
+				slog.Warn("books don't balance", "job", "reconcile", "discrepancies", len(r.Discrepancies), "debits", r.Debits, "credits", r.Credits)
+			}
+		}
+	}()
+
+	// Drop the audit entries older than the retention period.
+	go func() {
+		ticker := time.NewTicker(conf.Audit.PruneInterval)
+		defer ticker.Stop()
+
+		hc.Beat("audit", conf.Audit.PruneInterval)
+		for range ticker.C {
+			hc.Beat("audit", conf.Audit.PruneInterval)
+			n, err := audit.Prune(context.Background(), dbConn, time.Now().Add(-conf.Audit.Retention))
+			if err != nil {
+				slog.Error("couldn't prune audit log", "job", "audit", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("pruned audit log", "job", "audit", "count", n)
+			}
+		}
+	}()
 
 	server := http.Server{
 		Addr:    conf.REST.Host + ":" + conf.REST.Port,
-		Handler: handlers.API(dbConn),
+		Handler: handlers.API(dbConn, conf, tokens, hc),
 	}
 
+	// Everything the service needs is loaded.
+	hc.Started()
+
 	// We want to report the listener is closed.
 	var wg sync.WaitGroup
 	wg.Add(1)
 
 	// Start the listener.
 	go func() {
-		log.Printf("startup : Listening %s:%s", conf.REST.Host, conf.REST.Port)
-		log.Printf("shutdown : Listener closed : %v", server.ListenAndServe())
+		slog.Info("startup : Listening", "addr", server.Addr)
+		slog.Info("shutdown : Listener closed", "error", server.ListenAndServe())
 		wg.Done()
 	}()
 
@@ -68,6 +238,12 @@ func main() {
 	// Wait for a signal to shutdown.
 	<-osSignals
 
+	// Go unready first and give the load balancers time to notice before the
+	// listener closes.
+	hc.Drain()
+	slog.Info("shutdown : Draining", "delay", conf.REST.DrainDelay)
+	time.Sleep(conf.REST.DrainDelay)
+
 	// Create a context to attempt a graceful 5 second shutdown.
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
 	defer cancel()
@@ -75,15 +251,29 @@ func main() {
 	// Attempt the graceful shutdown by closing the listener and
 	// completing all inflight requests.
 	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("shutdown : Graceful shutdown did not complete in %v : %v", time.Duration(5*time.Second), err)
+		slog.Error("shutdown : Graceful shutdown did not complete", "timeout", time.Duration(5*time.Second), "error", err)
 
 		// Looks like we timedout on the graceful shutdown. Kill it hard.
 		if err := server.Close(); err != nil {
-			log.Printf("shutdown : Error killing server : %v", err)
+			slog.Error("shutdown : Error killing server", "error", err)
 		}
 	}
 
 	// Wait for the listener to report it is closed.
 	wg.Wait()
-	log.Println("main : Completed")
+
+	// Export the spans of the last requests.
+	tctx, tcancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer tcancel()
+	if err := shutdownTracing(tctx); err != nil {
+		slog.Error("shutdown : Couldn't flush traces", "error", err)
+	}
+
+	slog.Info("main : Completed")
+}
+
+// fatal logs why the service can't start and exits.
+func fatal(msg string, err error) {
+	slog.Error("main : "+msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/audit"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+func RunTestAudit(t *testing.T) {
+	t.Run("getAuditRedacted", getAuditRedacted)
+	t.Run("getAuditSigned", getAuditSigned)
+}
+
+func getAudit(t *testing.T, query string) handlers.GetAudit {
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/audit?"+query, nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got handlers.GetAudit
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	return got
+}
+
+func getAuditRedacted(t *testing.T) {
+	body := []byte(`{"name": "Audited", "password": "hunter2"}`)
+	r := httptest.NewRequest(http.MethodPost, "/api/user/create", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+	traceID := w.Header().Get(rest.TraceIDHeader)
+
+	got := getAudit(t, "route=/api/user/create&limit=1")
+	assert.Equal(t, 1, len(got.Entries))
+
+	e := got.Entries[0]
+	assert.Equal(t, traceID, e.TraceID)
+	assert.Equal(t, rest.AuthNone, e.AuthMethod)
+	assert.Equal(t, audit.Anonymous, e.Actor)
+	assert.NotEmpty(t, e.RemoteAddr)
+	assert.Equal(t, http.MethodPost, e.Method)
+	assert.Equal(t, http.StatusOK, e.Status)
+
+	var logged map[string]string
+	err := json.Unmarshal(e.Body, &logged)
+	assert.NoError(t, err)
+	assert.Equal(t, "Audited", logged["name"])
+	assert.Equal(t, audit.Redacted, logged["password"])
+}
+
+func getAuditSigned(t *testing.T) {
+	w := seamlessRequest(t, "/api/seamless/balance", handlers.PostSeamlessBalance{UserID: "unknown"})
+	assert.Equal(t, http.StatusNotFound, w.Code, http.StatusText(w.Code))
+
+	got := getAudit(t, "route=/api/seamless/balance&limit=1")
+	assert.Equal(t, 1, len(got.Entries))
+	assert.Equal(t, rest.AuthHMAC, got.Entries[0].AuthMethod)
+	assert.Equal(t, rest.SignatureActor, got.Entries[0].Actor)
+	assert.Equal(t, http.StatusNotFound, got.Entries[0].Status)
+
+	// reads aren't audited
+	got = getAudit(t, "method=GET")
+	assert.Equal(t, 0, got.Total)
+}
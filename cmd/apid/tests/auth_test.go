@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+const (
+	adminToken   = "admin-token"
+	supportToken = "support-token"
+)
+
+var authTokens, _ = rest.ParseTokens("ops:admin:" + adminToken + ",agent:support:" + supportToken)
+
+func RunTestAuth(t *testing.T) {
+	t.Run("adminUnauthenticated", getAdminUnauthenticated)
+	t.Run("adminForbidden", getAdminForbidden)
+	t.Run("adminAudited", postAdminAudited)
+}
+
+// adminRequest sends a request to an admin route with the authorization
+// header auth, if any.
+func adminRequest(method, path, auth string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	if auth != "" {
+		r.Header.Set(rest.AuthorizationHeader, auth)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+
+	return w
+}
+
+func getAdminUnauthenticated(t *testing.T) {
+	for _, path := range []string{"/api/v1/admin/fees", "/api/admin/fees"} {
+		for _, auth := range []string{"", "Bearer wrong", adminToken} {
+			w := adminRequest(http.MethodGet, path, auth)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, "%s with %q", path, auth)
+		}
+	}
+}
+
+func getAdminForbidden(t *testing.T) {
+	for _, path := range []string{"/api/v1/admin/fees", "/api/admin/fees"} {
+		w := adminRequest(http.MethodGet, path, "Bearer "+supportToken)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+
+		w = adminRequest(http.MethodGet, path, "Bearer "+adminToken)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+}
+
+func postAdminAudited(t *testing.T) {
+	w := adminRequest(http.MethodPost, "/api/v1/admin/reconciliation", "Bearer "+adminToken)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	got := getAudit(t, "route=/api/v1/admin/reconciliation&limit=1")
+	if assert.Equal(t, 1, len(got.Entries)) {
+		assert.Equal(t, "ops", got.Entries[0].Actor)
+		assert.Equal(t, rest.AuthToken, got.Entries[0].AuthMethod)
+	}
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/limit"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+func RunTestBatch(t *testing.T) {
+	t.Run("postBatch", postBatch)
+	t.Run("postBatchFailure", postBatchFailure)
+	t.Run("postBatchFailureMessage", postBatchFailureMessage)
+	t.Run("postBatchValidate", postBatchValidate)
+}
+
+func batchRequest(t *testing.T, b handlers.PostBatch) *httptest.ResponseRecorder {
+	body, err := json.Marshal(b)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/batch", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	return w
+}
+
+func postBatch(t *testing.T) {
+	winnerID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Ana"})
+	assert.NoError(t, err)
+
+	w := batchRequest(t, handlers.PostBatch{Operations: []handlers.PostBatchOperation{
+		{Op: ledger.TypeDeposit, UserID: winnerID, Amount: 300},
+		{Op: ledger.TypeDeposit, UserID: winnerID, Amount: 200},
+	}})
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got handlers.PostBatchResponse
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.True(t, got.Committed)
+	if assert.Len(t, got.Results, 2) {
+		assert.Equal(t, handlers.BatchOK, got.Results[1].Status)
+		assert.Equal(t, int64(200), got.Results[1].Transaction.Amount)
+	}
+
+	balance, err := user.GetBalanceByID(tests.Context(), test.MasterDB, winnerID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), balance)
+}
+
+func postBatchFailure(t *testing.T) {
+	winnerID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Ana"})
+	assert.NoError(t, err)
+
+	w := batchRequest(t, handlers.PostBatch{Operations: []handlers.PostBatchOperation{
+		{Op: ledger.TypeDeposit, UserID: winnerID, Amount: 300},
+		{Op: ledger.TypeDeposit, UserID: "unknown", Amount: 200},
+		{Op: ledger.TypeWithdraw, UserID: winnerID, Amount: 100},
+	}})
+	assert.Equal(t, http.StatusNotFound, w.Code, http.StatusText(w.Code))
+
+	var got handlers.PostBatchResponse
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.False(t, got.Committed)
+	if assert.Len(t, got.Results, 3) {
+		assert.Equal(t, handlers.BatchRolledBack, got.Results[0].Status)
+		assert.Equal(t, handlers.BatchFailed, got.Results[1].Status)
+		assert.Equal(t, user.ErrNotFound.Error(), got.Results[1].Error)
+		assert.Equal(t, handlers.BatchSkipped, got.Results[2].Status)
+	}
+
+	balance, err := user.GetBalanceByID(tests.Context(), test.MasterDB, winnerID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), balance)
+}
+
+func postBatchFailureMessage(t *testing.T) {
+	winnerID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Ana"})
+	assert.NoError(t, err)
+	err = limit.Set(tests.Context(), test.MasterDB, limit.Limits{ID: winnerID, MaxAmount: 100})
+	assert.NoError(t, err)
+
+	w := batchRequest(t, handlers.PostBatch{Operations: []handlers.PostBatchOperation{
+		{Op: ledger.TypeDeposit, UserID: winnerID, Amount: 150},
+	}})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, http.StatusText(w.Code))
+
+	var got handlers.PostBatchResponse
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	if assert.Len(t, got.Results, 1) {
+		assert.Equal(t, "maximum amount is 100: transaction limit exceeded", got.Results[0].Error)
+		assert.Equal(t, "LIMIT_EXCEEDED", got.Results[0].Code)
+	}
+}
+
+func postBatchValidate(t *testing.T) {
+	w := batchRequest(t, handlers.PostBatch{Operations: []handlers.PostBatchOperation{
+		{Op: ledger.TypeTransfer, UserID: userID, Amount: 1},
+	}})
+	assert.Equal(t, http.StatusBadRequest, w.Code, http.StatusText(w.Code))
+
+	var got rest.JSONError
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, rest.ErrValidation.Error(), got.Error)
+	assert.Equal(t, "operations", got.Fields[0].Fld)
+}
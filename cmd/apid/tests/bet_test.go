@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var (
+	betUserID string
+	betID     string
+)
+
+func RunTestBet(t *testing.T) {
+	var err error
+	betUserID, err = user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(tests.Context(), test.MasterDB, betUserID, 1000)
+	assert.NoError(t, err)
+
+	t.Run("postBet", postBet)
+	t.Run("postBetValidateOdds", postBetValidateOdds)
+	t.Run("postMarketSettle", postMarketSettle)
+}
+
+func postBet(t *testing.T) {
+	betPlace := handlers.PostBet{
+		ID:        betUserID,
+		Market:    "match-10",
+		Selection: "home",
+		Stake:     200,
+		Odds:      150,
+	}
+	body, err := json.Marshal(betPlace)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/bet", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got user.Bet
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, user.BetOpen, got.Status)
+	betID = got.ID
+}
+
+func postBetValidateOdds(t *testing.T) {
+	betPlace := handlers.PostBet{
+		ID:        betUserID,
+		Market:    "match-10",
+		Selection: "home",
+		Stake:     200,
+		Odds:      100,
+	}
+	body, err := json.Marshal(betPlace)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/bet", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code, http.StatusText(w.Code))
+}
+
+func postMarketSettle(t *testing.T) {
+	marketSettle := handlers.PostMarketSettle{
+		Outcomes: map[string]string{"home": user.BetWin},
+	}
+	body, err := json.Marshal(marketSettle)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/market/match-10/settle", bytes.NewBuffer(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	r = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/bet/%s", betID), nil)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got user.Bet
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, user.BetWin, got.Status)
+	assert.Equal(t, int64(300), got.Payout)
+
+	balance, err := user.GetBalanceByID(tests.Context(), test.MasterDB, betUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1100), balance)
+}
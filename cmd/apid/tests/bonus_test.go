@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var bonusUserID string
+
+func RunTestBonus(t *testing.T) {
+	var err error
+	bonusUserID, err = user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Kim"})
+	assert.NoError(t, err)
+
+	t.Run("postBonus", postBonus)
+	t.Run("postBonusActive", postBonusActive)
+	t.Run("getUserBonuses", getUserBonuses)
+}
+
+func postBonusRequest(t *testing.T, amount int64) *httptest.ResponseRecorder {
+	bonusGrant := handlers.PostBonus{
+		ID:        bonusUserID,
+		Amount:    amount,
+		Wagering:  3,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	body, err := json.Marshal(bonusGrant)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/bonus", bytes.NewBuffer(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+
+	return w
+}
+
+func postBonus(t *testing.T) {
+	w := postBonusRequest(t, 500)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got user.Bonus
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), got.Required)
+
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/balance/%s", bonusUserID), nil)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var balance handlers.GetUserBalance
+	err = json.NewDecoder(w.Body).Decode(&balance)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), balance.Balance)
+	assert.Equal(t, int64(500), balance.Bonus)
+}
+
+func postBonusActive(t *testing.T) {
+	w := postBonusRequest(t, 100)
+	assert.Equal(t, http.StatusConflict, w.Code, http.StatusText(w.Code))
+}
+
+func getUserBonuses(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/bonus/%s", bonusUserID), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var bonuses []user.Bonus
+	err := json.NewDecoder(w.Body).Decode(&bonuses)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(bonuses))
+	assert.Equal(t, user.BonusActive, bonuses[0].Status)
+}
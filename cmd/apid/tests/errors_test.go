@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+func RunTestErrors(t *testing.T) {
+	t.Run("codes", postErrorCodes)
+	t.Run("problem", postErrorProblem)
+	t.Run("websocket", wsErrorCode)
+	t.Run("spec", getErrorSpec)
+}
+
+// postWithdraw withdraws amount from the wallet of userID.
+func postWithdraw(t *testing.T, userID string, amount int64, header http.Header) *httptest.ResponseRecorder {
+	body, err := json.Marshal(handlers.PostUserAmount{ID: userID, Amount: amount})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/wallet/withdraw", bytes.NewBuffer(body))
+	for k, v := range header {
+		r.Header.Set(k, strings.Join(v, ", "))
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+
+	return w
+}
+
+func postErrorCodes(t *testing.T) {
+	userID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Ana"})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		userID string
+		amount int64
+		status int
+		code   string
+	}{
+		{userID, 1, http.StatusPaymentRequired, "INSUFFICIENT_FUNDS"},
+		{"missing", 1, http.StatusNotFound, "USER_NOT_FOUND"},
+		{userID, 0, http.StatusBadRequest, rest.CodeValidation},
+	}
+	for _, c := range cases {
+		w := postWithdraw(t, c.userID, c.amount, nil)
+		assert.Equal(t, c.status, w.Code, http.StatusText(w.Code))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var got rest.JSONError
+		err := json.NewDecoder(w.Body).Decode(&got)
+		assert.NoError(t, err)
+		assert.Equal(t, c.code, got.Code)
+		assert.NotEmpty(t, got.Error)
+	}
+}
+
+func postErrorProblem(t *testing.T) {
+	userID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Ana"})
+	assert.NoError(t, err)
+
+	w := postWithdraw(t, userID, 1, http.Header{
+		"Accept":           {"application/json;q=0.9, application/problem+json"},
+		rest.TraceIDHeader: {"trace-problem"},
+	})
+	assert.Equal(t, http.StatusPaymentRequired, w.Code, http.StatusText(w.Code))
+	assert.Equal(t, rest.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var got rest.Problem
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, rest.Problem{
+		Type:     rest.ProblemTypePrefix + "insufficient-funds",
+		Title:    "Insufficient funds",
+		Status:   http.StatusPaymentRequired,
+		Detail:   user.ErrInsufficientFunds.Error(),
+		Instance: "trace-problem",
+		Code:     "INSUFFICIENT_FUNDS",
+	}, got)
+
+	// Validation errors keep their fields.
+	w = postWithdraw(t, userID, 0, http.Header{"Accept": {rest.ProblemContentType}})
+	assert.Equal(t, http.StatusBadRequest, w.Code, http.StatusText(w.Code))
+
+	got = rest.Problem{}
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, rest.CodeValidation, got.Code)
+	assert.NotEmpty(t, got.Fields)
+	assert.NotEmpty(t, got.Instance)
+}
+
+func wsErrorCode(t *testing.T) {
+	app := rest.New(rest.ErrorHandlerMiddleware)
+	app.WebsocketHandle("/ws/frozen", func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		return errors.Wrap(user.ErrAccountFrozen, "")
+	})
+	s := httptest.NewServer(app)
+	defer s.Close()
+
+	u := strings.Replace(s.URL, "http", "ws", 1) + "/ws/frozen"
+	ws, _, err := websocket.DefaultDialer.Dial(u, nil)
+	assert.NoError(t, err)
+	defer ws.Close()
+
+	_, _, err = ws.ReadMessage()
+	cerr, ok := err.(*websocket.CloseError)
+	if !assert.True(t, ok, "close error expected, got %v", err) {
+		return
+	}
+	assert.Equal(t, websocket.CloseInternalServerErr, cerr.Code)
+
+	var got rest.JSONError
+	err = json.Unmarshal([]byte(cerr.Text), &got)
+	assert.NoError(t, err)
+	assert.Equal(t, rest.JSONError{Error: user.ErrAccountFrozen.Error(), Code: "ACCOUNT_FROZEN"}, got)
+}
+
+func getErrorSpec(t *testing.T) {
+	spec := a.OpenAPI("", "")
+
+	res := spec.Paths["/api/v1/wallet/withdraw"]["post"].Responses["default"]
+	assert.Equal(t, "#/components/schemas/rest.Problem", res.Content[rest.ProblemContentType].Schema.Ref)
+
+	codes := spec.Components.Schemas["rest.JSONError"].Properties["code"].Enum
+	for _, c := range []string{"INSUFFICIENT_FUNDS", "ACCOUNT_FROZEN", "LIMIT_EXCEEDED", rest.CodeInternal} {
+		assert.Contains(t, codes, c)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/fee"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var feeUserID string
+
+func RunTestFee(t *testing.T) {
+	var err error
+	feeUserID, err = user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Alex", Currency: "USD"})
+	assert.NoError(t, err)
+
+	t.Run("putFee", putFee)
+	t.Run("getFeeQuote", getFeeQuote)
+	t.Run("getFeeQuoteInvalidOp", getFeeQuoteInvalidOp)
+}
+
+func putFee(t *testing.T) {
+	schedule := fee.Schedule{
+		Op:       ledger.TypeWithdraw,
+		Currency: "USD",
+		Fixed:    25,
+		Rate:     100,
+	}
+	body, err := json.Marshal(schedule)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, "/api/admin/fees", bytes.NewBuffer(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	r = httptest.NewRequest(http.MethodGet, "/api/admin/fees", nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got []fee.Schedule
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(got))
+	assert.Equal(t, "USD", got[0].Currency)
+}
+
+func getFeeQuote(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/fee/%s?op=withdraw&amount=1000", feeUserID), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got fee.Quote
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(35), got.Fee)
+	assert.Equal(t, int64(1035), got.Total)
+}
+
+func getFeeQuoteInvalidOp(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/fee/%s?op=deposit&amount=1000", feeUserID), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code, http.StatusText(w.Code))
+}
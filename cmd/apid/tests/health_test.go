@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/health"
+)
+
+// RunTestHealth drains the service, so it runs last.
+func RunTestHealth(t *testing.T) {
+	t.Run("getHealthz", getHealthz)
+	t.Run("getReadyzStarting", getReadyzStarting)
+	t.Run("getReadyz", getReadyz)
+	t.Run("getReadyzDraining", getReadyzDraining)
+}
+
+func readyz(t *testing.T, status int) health.Report {
+	r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, status, w.Code, http.StatusText(w.Code))
+
+	var got health.Report
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	return got
+}
+
+func getHealthz(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+}
+
+func getReadyzStarting(t *testing.T) {
+	got := readyz(t, http.StatusServiceUnavailable)
+	assert.Equal(t, "not finished", got.Checks[health.CheckStartup])
+}
+
+func getReadyz(t *testing.T) {
+	hc.Started()
+
+	got := readyz(t, http.StatusOK)
+	assert.Equal(t, health.StatusOK, got.Status)
+	assert.Equal(t, health.StatusOK, got.Checks[health.CheckStorage])
+}
+
+func getReadyzDraining(t *testing.T) {
+	hc.Drain()
+
+	got := readyz(t, http.StatusServiceUnavailable)
+	assert.Equal(t, "draining", got.Checks[health.CheckShutdown])
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var (
+	holdUserID string
+	holdID     string
+)
+
+func RunTestHold(t *testing.T) {
+	var err error
+	holdUserID, err = user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(tests.Context(), test.MasterDB, holdUserID, 1000)
+	assert.NoError(t, err)
+
+	t.Run("postHold", postHold)
+	t.Run("getUserBalanceHeld", getUserBalanceHeld)
+	t.Run("postHoldCapture", postHoldCapture)
+	t.Run("postHoldVoidCaptured", postHoldVoidCaptured)
+}
+
+func postHold(t *testing.T) {
+	holdCreate := handlers.PostHold{
+		ID:        holdUserID,
+		Amount:    400,
+		ExpiresIn: 60,
+		Reference: "round-1",
+	}
+	body, err := json.Marshal(holdCreate)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/hold", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got user.Hold
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, user.HoldActive, got.Status)
+	holdID = got.ID
+}
+
+func getUserBalanceHeld(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/balance/%s", holdUserID), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got handlers.GetUserBalance
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), got.Balance)
+	assert.Equal(t, int64(600), got.Available)
+	assert.Equal(t, int64(400), got.Held)
+}
+
+func postHoldCapture(t *testing.T) {
+	body, err := json.Marshal(handlers.PostHoldCapture{Amount: 150})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/wallet/hold/%s/capture", holdID), bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	balance, err := user.GetBalanceByID(tests.Context(), test.MasterDB, holdUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(850), balance)
+}
+
+func postHoldVoidCaptured(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/wallet/hold/%s/void", holdID), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusConflict, w.Code, http.StatusText(w.Code))
+}
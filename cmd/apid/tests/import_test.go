@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/importer"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+func RunTestImport(t *testing.T) {
+	t.Run("postImport", postImport)
+	t.Run("postImportDryRun", postImportDryRun)
+	t.Run("postImportInvalidFormat", postImportInvalidFormat)
+	t.Run("getImportNotFound", getImportNotFound)
+}
+
+const importNDJSON = `{"name":"Ana","email":"ana@import.test","balance":700}
+{"name":"","email":"blank@import.test"}
+`
+
+// waitImport polls the import job until it's no longer running.
+func waitImport(t *testing.T, id string) importer.Job {
+	var got importer.Job
+	for i := 0; i < 100; i++ {
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/admin/import/%s", id), nil)
+		r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+		err := json.NewDecoder(w.Body).Decode(&got)
+		assert.NoError(t, err)
+		if got.Status != importer.StatusRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return got
+}
+
+func postImport(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/import", strings.NewReader(importNDJSON))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusAccepted, w.Code, http.StatusText(w.Code))
+
+	var got importer.Job
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, got.Total)
+
+	got = waitImport(t, got.ID)
+	assert.Equal(t, importer.StatusDone, got.Status)
+	assert.Equal(t, 1, got.Imported)
+	assert.Equal(t, []importer.RowError{{Line: 2, Field: "name", Error: "cannot be blank"}}, got.Errors)
+
+	u, err := user.GetByEmail(tests.Context(), test.MasterDB, "ana@import.test")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(700), u.Balance)
+}
+
+func postImportDryRun(t *testing.T) {
+	body := "name,email,balance\nBo,bo@import.test,50\n"
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/import?format=csv&dry_run=true", strings.NewReader(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusAccepted, w.Code, http.StatusText(w.Code))
+
+	var got importer.Job
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+
+	got = waitImport(t, got.ID)
+	assert.True(t, got.DryRun)
+	assert.Equal(t, 1, got.Imported)
+
+	_, err = user.GetByEmail(tests.Context(), test.MasterDB, "bo@import.test")
+	assert.Equal(t, user.ErrNotFound, err)
+}
+
+func postImportInvalidFormat(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/import", strings.NewReader(importNDJSON))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code, http.StatusText(w.Code))
+
+	var got rest.JSONError
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, importer.ErrInvalidFormat.Error(), got.Error)
+}
+
+func getImportNotFound(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/import/unknown", nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code, http.StatusText(w.Code))
+}
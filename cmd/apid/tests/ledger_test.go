@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+func RunTestLedger(t *testing.T) {
+	t.Run("getSnapshot", getSnapshot)
+}
+
+func getSnapshot(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/ledger/snapshot", nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got ledger.Snapshot
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, got.Transactions)
+
+	// the chain survives the round trip through JSON
+	assert.Nil(t, ledger.Verify(got, nil))
+}
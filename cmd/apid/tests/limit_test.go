@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/limit"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var limitUserID string
+
+func RunTestLimit(t *testing.T) {
+	var err error
+	limitUserID, err = user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+
+	t.Run("putLimits", putLimits)
+	t.Run("getLimits", getLimits)
+	t.Run("postUserDepositLimitExceeded", postUserDepositLimitExceeded)
+	t.Run("putLimitsNegative", putLimitsNegative)
+	t.Run("putLimitsUnknownUser", putLimitsUnknownUser)
+	t.Run("deleteLimits", deleteLimits)
+}
+
+func putLimits(t *testing.T) {
+	l := limit.Limits{
+		MaxAmount: 500,
+	}
+	body, err := json.Marshal(l)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/admin/limits/%s", limitUserID), bytes.NewBuffer(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+}
+
+func getLimits(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/admin/limits/%s", limitUserID), nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got limit.Limits
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), got.MaxAmount)
+}
+
+func postUserDepositLimitExceeded(t *testing.T) {
+	userAmount := handlers.PostUserAmount{
+		ID:     limitUserID,
+		Amount: 501,
+	}
+	body, err := json.Marshal(userAmount)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/deposit", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, http.StatusText(w.Code))
+
+	var got rest.JSONError
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, "maximum amount is 500: transaction limit exceeded", got.Error)
+}
+
+func putLimitsNegative(t *testing.T) {
+	l := limit.Limits{
+		MaxAmount: -2,
+		Daily:     limit.Window{Withdraw: -100},
+	}
+	body, err := json.Marshal(l)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/admin/limits/%s", limitUserID), bytes.NewBuffer(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code, http.StatusText(w.Code))
+
+	var got rest.JSONError
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Len(t, got.Fields, 2)
+}
+
+func putLimitsUnknownUser(t *testing.T) {
+	body, err := json.Marshal(limit.Limits{MaxAmount: 500})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/admin/limits/unknown", bytes.NewBuffer(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code, http.StatusText(w.Code))
+}
+
+func deleteLimits(t *testing.T) {
+	r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/admin/limits/%s", limitUserID), nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code, http.StatusText(w.Code))
+
+	// The global limits apply again.
+	r = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/admin/limits/%s", limitUserID), nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got limit.Limits
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), got.MaxAmount)
+
+	body, err := json.Marshal(handlers.PostUserAmount{ID: limitUserID, Amount: 501})
+	assert.NoError(t, err)
+
+	r = httptest.NewRequest(http.MethodPost, "/api/v1/wallet/deposit", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+}
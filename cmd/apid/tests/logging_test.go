@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+func RunTestLogging(t *testing.T) {
+	t.Run("requestContext", logRequestContext)
+	t.Run("invalidFormat", logInvalidFormat)
+}
+
+// logBuffer is safe to log to from the notifier's goroutines too.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) lines(t *testing.T) []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []map[string]interface{}
+	s := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for s.Scan() {
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(s.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func logRequestContext(t *testing.T) {
+	var buf logBuffer
+	l, err := rest.NewLogger(&buf, "info", rest.LogJSON)
+	assert.NoError(t, err)
+	rest.SetLogger(l)
+	defer rest.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	userID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Ana"})
+	assert.NoError(t, err)
+
+	body, err := json.Marshal(handlers.PostUserAmount{ID: userID, Amount: 100})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/withdraw", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code, http.StatusText(w.Code))
+
+	traceID := w.Header().Get(rest.TraceIDHeader)
+
+	// Clients' mistakes, like this one, are only logged as requests.
+	lines := buf.lines(t)
+	if assert.Len(t, lines, 1) {
+		assert.Equal(t, "request", lines[0]["msg"])
+	}
+	for _, line := range lines {
+		assert.Equal(t, traceID, line["trace_id"])
+		assert.Equal(t, "/api/wallet/withdraw", line["route"])
+		assert.Equal(t, userID, line["user_id"])
+		assert.Equal(t, float64(http.StatusPaymentRequired), line["status"])
+	}
+}
+
+func logInvalidFormat(t *testing.T) {
+	_, err := rest.NewLogger(&bytes.Buffer{}, "info", "xml")
+	assert.Equal(t, rest.ErrInvalidLogFormat, err)
+
+	_, err = rest.NewLogger(&bytes.Buffer{}, "loud", rest.LogText)
+	assert.Error(t, err)
+}
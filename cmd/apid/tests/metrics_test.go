@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func RunTestMetrics(t *testing.T) {
+	t.Run("getMetrics", getMetrics)
+}
+
+func getMetrics(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`http_requests_total{method="POST",route="/api/wallet/deposit",status="200"}`,
+		`http_request_duration_seconds_bucket{method="GET",route="/api/user/{userID}",status="404",le="+Inf"}`,
+		`wallet_operations_total{op="withdraw",outcome="insufficient_funds"}`,
+		`wallet_volume_total{op="deposit"}`,
+		`wallet_insufficient_funds_total{op="withdraw"}`,
+		`websocket_connections{topic="/ws/topic/outcomes"}`,
+		`websocket_messages_total{topic="/ws/topic/outcomes"}`,
+		`memdb_write_txn_duration_seconds_count`,
+	} {
+		assert.Contains(t, body, want)
+	}
+}
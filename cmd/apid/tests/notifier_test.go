@@ -60,21 +60,32 @@ func wsNotifierOutcomes(t *testing.T) {
 	assert.NoError(t, err)
 	defer ws.Close()
 
-	messageType, message, err := ws.ReadMessage()
+	// settle a bet to produce an outcome
+	ctx := tests.Context()
+	userID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "John"})
 	assert.NoError(t, err)
-	assert.Equal(t, websocket.TextMessage, messageType)
-
-	var users []user.User
-	err = json.Unmarshal(message, &users)
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, 100)
 	assert.NoError(t, err)
-	assert.True(t, len(users) > 2)
-
-	// change data to allow one more read
-	err = tests.SeedUser(context.TODO(), test.MasterDB, "John1", 100)
+	bet, err := user.PlaceBet(ctx, test.MasterDB, user.Bet{
+		UserID:    userID,
+		Market:    "match-1",
+		Selection: "home",
+		Stake:     100,
+		Odds:      300,
+	})
+	assert.NoError(t, err)
+	_, err = user.SettleBet(ctx, test.MasterDB, bet.ID, user.BetWin)
 	assert.NoError(t, err)
 
-	// test one more read
-	messageType, message, err = ws.ReadMessage()
+	messageType, message, err := ws.ReadMessage()
 	assert.NoError(t, err)
 	assert.Equal(t, websocket.TextMessage, messageType)
+
+	var bets []user.Bet
+	err = json.Unmarshal(message, &bets)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(bets))
+	assert.Equal(t, bet.ID, bets[0].ID)
+	assert.Equal(t, user.BetWin, bets[0].Status)
+	assert.Equal(t, int64(300), bets[0].Payout)
 }
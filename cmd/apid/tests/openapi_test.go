@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+func RunTestOpenAPI(t *testing.T) {
+	t.Run("drift", openAPIDrift)
+	t.Run("getOpenAPI", getOpenAPI)
+}
+
+// openAPIDrift fails when a route is added without a doc, a doc is left
+// for a removed route or the spec misses a route the router has.
+func openAPIDrift(t *testing.T) {
+	assert.Empty(t, a.Drift())
+
+	spec := a.OpenAPI("", "")
+	err := a.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, m := range methods {
+			_, ok := spec.Paths[path][strings.ToLower(m)]
+			assert.True(t, ok, "%s %s isn't in the spec", m, path)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func getOpenAPI(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var spec rest.Spec
+	err := json.NewDecoder(w.Body).Decode(&spec)
+	assert.NoError(t, err)
+	assert.Equal(t, rest.OpenAPIVersion, spec.OpenAPI)
+
+	deposit := spec.Paths["/api/wallet/deposit"]["post"]
+	if assert.NotNil(t, deposit) && assert.NotNil(t, deposit.RequestBody) {
+		assert.Equal(t, "#/components/schemas/handlers.PostUserAmount", deposit.RequestBody.Content["application/json"].Schema.Ref)
+		assert.Equal(t, "#/components/schemas/rest.JSONError", deposit.Responses["default"].Content["application/json"].Schema.Ref)
+	}
+
+	// The constraints come from the validation rules.
+	schemas := spec.Components.Schemas
+	if amount := schemas["handlers.PostUserAmount"]; assert.NotNil(t, amount) {
+		assert.Equal(t, []string{"amount"}, amount.Required)
+		if assert.NotNil(t, amount.Properties["amount"].Minimum) {
+			assert.Equal(t, float64(1), *amount.Properties["amount"].Minimum)
+		}
+	}
+	if u := schemas["user.User"]; assert.NotNil(t, u) {
+		assert.Equal(t, []string{"name"}, u.Required)
+		if assert.NotNil(t, u.Properties["name"].MaxLength) {
+			assert.Equal(t, 100, *u.Properties["name"].MaxLength)
+		}
+		assert.Equal(t, "email", u.Properties["email"].Format)
+		assert.Equal(t, "date-time", u.Properties["created_at"].Format)
+	}
+	if st := schemas["handlers.PutUserStatus"]; assert.NotNil(t, st) {
+		assert.Equal(t, []interface{}{"active", "frozen", "suspended", "closed"}, st.Properties["status"].Enum)
+	}
+	if b := schemas["handlers.PostBatch"]; assert.NotNil(t, b) {
+		assert.Equal(t, "array", b.Properties["operations"].Type)
+		assert.NotNil(t, b.Properties["operations"].MaxItems)
+	}
+
+	get := spec.Paths["/api/user/{userID}"]["get"]
+	if assert.NotNil(t, get) && assert.Len(t, get.Parameters, 1) {
+		assert.Equal(t, "userID", get.Parameters[0].Name)
+		assert.Equal(t, "path", get.Parameters[0].In)
+	}
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/reconcile"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+func RunTestReconciliation(t *testing.T) {
+	t.Run("postReconciliation", postReconciliation)
+	t.Run("getReconciliation", getReconciliation)
+}
+
+var reconciliationID string
+
+func postReconciliation(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/reconciliation", nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got reconcile.Report
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.True(t, got.Balanced, "discrepancies: %v", got.Discrepancies)
+	reconciliationID = got.ID
+}
+
+func getReconciliation(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/reconciliation", nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got reconcile.Report
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, reconciliationID, got.ID)
+}
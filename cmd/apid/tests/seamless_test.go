@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+const (
+	seamlessSecret = "provider-secret"
+	seamlessWindow = 5 * time.Minute
+)
+
+var seamlessUserID string
+
+func RunTestSeamless(t *testing.T) {
+	var err error
+	seamlessUserID, err = user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Sam"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(tests.Context(), test.MasterDB, seamlessUserID, 1000)
+	assert.NoError(t, err)
+
+	t.Run("postSeamlessUnsigned", postSeamlessUnsigned)
+	t.Run("postSeamlessReplayed", postSeamlessReplayed)
+	t.Run("postSeamlessTooLarge", postSeamlessTooLarge)
+	t.Run("postSeamlessZeroAmount", postSeamlessZeroAmount)
+	t.Run("postSeamlessRound", postSeamlessRound)
+	t.Run("postSeamlessRollbackFirst", postSeamlessRollbackFirst)
+}
+
+// seamlessRequest sends v signed with the shared secret now.
+func seamlessRequest(t *testing.T, path string, v interface{}) *httptest.ResponseRecorder {
+	body, err := json.Marshal(v)
+	assert.NoError(t, err)
+
+	return signedRequest(path, body, time.Now())
+}
+
+// signedRequest sends body signed with the shared secret at signedAt.
+func signedRequest(path string, body []byte, signedAt time.Time) *httptest.ResponseRecorder {
+	ts := strconv.FormatInt(signedAt.Unix(), 10)
+
+	r := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+	r.Header.Set(rest.SignatureHeader, sign(ts, body))
+	r.Header.Set(rest.SignatureTimestampHeader, ts)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+
+	return w
+}
+
+// sign returns the signature of body sent with the timestamp ts.
+func sign(ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(seamlessSecret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func postSeamlessUnsigned(t *testing.T) {
+	body, err := json.Marshal(handlers.PostSeamlessBalance{UserID: seamlessUserID})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/seamless/balance", bytes.NewBuffer(body))
+	r.Header.Set(rest.SignatureHeader, hex.EncodeToString([]byte("forged")))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, http.StatusText(w.Code))
+}
+
+func postSeamlessReplayed(t *testing.T) {
+	body, err := json.Marshal(handlers.PostSeamlessBalance{UserID: seamlessUserID})
+	assert.NoError(t, err)
+
+	for _, signedAt := range []time.Time{
+		time.Now().Add(-seamlessWindow - time.Minute),
+		time.Now().Add(seamlessWindow + time.Minute),
+	} {
+		w := signedRequest("/api/v1/seamless/balance", body, signedAt)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, http.StatusText(w.Code))
+	}
+
+	// The timestamp is signed too, it can't be refreshed.
+	old := strconv.FormatInt(time.Now().Add(-seamlessWindow-time.Minute).Unix(), 10)
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/seamless/balance", bytes.NewBuffer(body))
+	r.Header.Set(rest.SignatureHeader, sign(old, body))
+	r.Header.Set(rest.SignatureTimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, http.StatusText(w.Code))
+}
+
+func postSeamlessTooLarge(t *testing.T) {
+	body := []byte(`{"user_id": "` + strings.Repeat("x", rest.SignatureMaxBody) + `"}`)
+
+	w := signedRequest("/api/v1/seamless/balance", body, time.Now())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, http.StatusText(w.Code))
+}
+
+func postSeamlessZeroAmount(t *testing.T) {
+	for _, path := range []string{"/api/v1/seamless/debit", "/api/v1/seamless/credit"} {
+		w := seamlessRequest(t, path, handlers.PostSeamlessTxn{
+			Provider:      "acme",
+			TransactionID: "zero",
+			RoundID:       "r-0",
+			UserID:        seamlessUserID,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func postSeamlessRound(t *testing.T) {
+	debit := handlers.PostSeamlessTxn{
+		Provider:      "acme",
+		TransactionID: "d-1",
+		RoundID:       "r-1",
+		UserID:        seamlessUserID,
+		Amount:        300,
+	}
+
+	// retries are answered with the original result
+	for i := 0; i < 2; i++ {
+		w := seamlessRequest(t, "/api/seamless/debit", debit)
+		assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+		var pt user.ProviderTxn
+		err := json.NewDecoder(w.Body).Decode(&pt)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(700), pt.Balance)
+	}
+
+	debit.Amount = 400
+	w := seamlessRequest(t, "/api/seamless/debit", debit)
+	assert.Equal(t, http.StatusConflict, w.Code, http.StatusText(w.Code))
+
+	w = seamlessRequest(t, "/api/seamless/credit", handlers.PostSeamlessTxn{
+		Provider:      "acme",
+		TransactionID: "c-1",
+		RoundID:       "r-1",
+		UserID:        seamlessUserID,
+		Amount:        500,
+	})
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	w = seamlessRequest(t, "/api/seamless/balance", handlers.PostSeamlessBalance{UserID: seamlessUserID})
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var balance handlers.GetSeamlessBalance
+	err := json.NewDecoder(w.Body).Decode(&balance)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1200), balance.Balance)
+}
+
+func postSeamlessRollbackFirst(t *testing.T) {
+	w := seamlessRequest(t, "/api/seamless/rollback", handlers.PostSeamlessRollback{
+		Provider:      "acme",
+		TransactionID: "rb-2",
+		RoundID:       "r-2",
+		UserID:        seamlessUserID,
+		RollbackOf:    "d-2",
+	})
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	w = seamlessRequest(t, "/api/seamless/debit", handlers.PostSeamlessTxn{
+		Provider:      "acme",
+		TransactionID: "d-2",
+		RoundID:       "r-2",
+		UserID:        seamlessUserID,
+		Amount:        100,
+	})
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var pt user.ProviderTxn
+	err := json.NewDecoder(w.Body).Decode(&pt)
+	assert.NoError(t, err)
+	assert.True(t, pt.RolledBack)
+	assert.Equal(t, int64(1200), pt.Balance)
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/statement"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+func RunTestStatement(t *testing.T) {
+	t.Run("getStatement", getStatement)
+	t.Run("getStatementCSV", getStatementCSV)
+	t.Run("getStatementValidate", getStatementValidate)
+}
+
+func getStatement(t *testing.T) {
+	month := time.Now().UTC().Format("2006-01")
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/statement/%s?month=%s", userID, month), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got statement.Statement
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+
+	balance, err := user.GetBalanceByID(tests.Context(), test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), got.Opening)
+	assert.Equal(t, balance, got.Closing)
+	assert.NotEmpty(t, got.Lines)
+}
+
+func getStatementCSV(t *testing.T) {
+	month := time.Now().UTC().Format("2006-01")
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/statement/%s?month=%s&format=csv", userID, month), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"date", "transaction_id", "type", "amount", "fee", "balance"}, rows[0])
+	assert.Equal(t, "closing_balance", rows[len(rows)-1][2])
+}
+
+func getStatementValidate(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/statement/%s?month=september", userID), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code, http.StatusText(w.Code))
+
+	var got rest.JSONError
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, "month", got.Fields[0].Fld)
+}
@@ -3,16 +3,20 @@ package tests
 import (
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"os"
 	"testing"
 
 	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/config"
+	"github.com/timurguseynov/go-wallet-api/internal/health"
 	"github.com/timurguseynov/go-wallet-api/internal/rest"
 	"github.com/timurguseynov/go-wallet-api/internal/tests"
 )
 
 var (
 	a    *rest.App
+	hc   *health.Checker
 	test *tests.Test
 )
 
@@ -26,16 +30,45 @@ func TestHandlers(t *testing.T) {
 	defer tests.Recover(t)
 
 	log.SetOutput(ioutil.Discard)
+	rest.SetLogger(slog.New(slog.NewTextHandler(ioutil.Discard, nil)))
 
 	t.Run("users", RunTestUser)
+	t.Run("holds", RunTestHold)
+	t.Run("bets", RunTestBet)
+	t.Run("bonuses", RunTestBonus)
+	t.Run("transactions", RunTestTransaction)
+	t.Run("limits", RunTestLimit)
+	t.Run("auth", RunTestAuth)
+	t.Run("fees", RunTestFee)
+	t.Run("seamless", RunTestSeamless)
+	t.Run("batches", RunTestBatch)
+	t.Run("imports", RunTestImport)
+	t.Run("statements", RunTestStatement)
+	t.Run("reconciliation", RunTestReconciliation)
+	t.Run("ledger", RunTestLedger)
+	t.Run("audit", RunTestAudit)
 	t.Run("notifier", RunTestNotifier)
+	t.Run("metrics", RunTestMetrics)
+	t.Run("logging", RunTestLogging)
+	t.Run("tracing", RunTestTracing)
+	t.Run("openapi", RunTestOpenAPI)
+	t.Run("versioning", RunTestVersioning)
+	t.Run("errors", RunTestErrors)
+	t.Run("health", RunTestHealth)
 }
 
 func testMain(m *testing.M) int {
 	test = tests.New()
 	defer test.TearDown()
 
-	a = handlers.API(test.MasterDB).(*rest.App)
+	var conf config.Config
+	conf.Seamless.Secret = seamlessSecret
+	conf.Seamless.SignatureWindow = seamlessWindow
+	conf.Legacy.Deprecated = legacyDeprecated
+	conf.Legacy.Sunset = legacySunset
+
+	hc = health.New(test.MasterDB)
+	a = handlers.API(test.MasterDB, conf, authTokens, hc).(*rest.App)
 
 	return m.Run()
 }
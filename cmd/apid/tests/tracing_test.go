@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/tracing"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+const (
+	remoteTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	remoteSpanID  = "00f067aa0ba902b7"
+)
+
+func RunTestTracing(t *testing.T) {
+	t.Run("traceparent", traceParent)
+	t.Run("traceIDHeader", traceIDHeader)
+}
+
+// span is the part of the spans the file exporter writes that's checked.
+type span struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		SpanID string
+	}
+}
+
+// traceDeposit makes a deposit with the headers in h, tracing to a file,
+// and returns the response and the spans exported.
+func traceDeposit(t *testing.T, h http.Header) (*httptest.ResponseRecorder, []span) {
+	userID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Ana"})
+	assert.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterFile, File: file})
+	assert.NoError(t, err)
+
+	body, err := json.Marshal(handlers.PostUserAmount{ID: userID, Amount: 100})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/deposit", bytes.NewBuffer(body))
+	for k := range h {
+		r.Header.Set(k, h.Get(k))
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	assert.NoError(t, shutdown(context.Background()))
+
+	f, err := os.Open(file)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	var spans []span
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var sp span
+		assert.NoError(t, json.Unmarshal(s.Bytes(), &sp))
+		spans = append(spans, sp)
+	}
+
+	return w, spans
+}
+
+func traceParent(t *testing.T) {
+	h := http.Header{}
+	h.Set("traceparent", "00-"+remoteTraceID+"-"+remoteSpanID+"-01")
+
+	w, spans := traceDeposit(t, h)
+	assert.Equal(t, remoteTraceID, w.Header().Get(rest.TraceIDHeader))
+
+	names := map[string]span{}
+	for _, sp := range spans {
+		assert.Equal(t, remoteTraceID, sp.SpanContext.TraceID, sp.Name)
+		names[sp.Name] = sp
+	}
+	for _, name := range []string{
+		"POST /api/wallet/deposit",
+		"rest.MetricsMiddleware",
+		"rest.RequestLoggerMiddleware",
+		"audit.Middleware",
+		"rest.ErrorHandlerMiddleware",
+		"handlers.(*User).postUserDeposit",
+		"memdb.txn",
+	} {
+		assert.Contains(t, names, name)
+	}
+
+	// The request continues the caller's span.
+	assert.Equal(t, remoteSpanID, names["POST /api/wallet/deposit"].Parent.SpanID)
+	assert.Equal(t, names["POST /api/wallet/deposit"].SpanContext.SpanID, names["rest.MetricsMiddleware"].Parent.SpanID)
+}
+
+func traceIDHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set(rest.TraceIDHeader, "caller-trace")
+
+	w, spans := traceDeposit(t, h)
+	assert.Equal(t, "caller-trace", w.Header().Get(rest.TraceIDHeader))
+	assert.NotEmpty(t, spans)
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var (
+	transactionUserID string
+	transactionID     string
+)
+
+func RunTestTransaction(t *testing.T) {
+	var err error
+	transactionUserID, err = user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(tests.Context(), test.MasterDB, transactionUserID, 1000)
+	assert.NoError(t, err)
+
+	t.Run("getUserTransactions", getUserTransactions)
+	t.Run("postTransactionReverse", postTransactionReverse)
+	t.Run("postTransactionReverseTwice", postTransactionReverseTwice)
+	t.Run("postTransactionReverseUnauthorized", postTransactionReverseUnauthorized)
+	t.Run("postTransactionReverseNotReversible", postTransactionReverseNotReversible)
+}
+
+func getUserTransactions(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/transactions/%s", transactionUserID), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got []ledger.Transaction
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(got))
+	assert.Equal(t, ledger.TypeDeposit, got[0].Type)
+	transactionID = got[0].ID
+}
+
+func postTransactionReverse(t *testing.T) {
+	body, err := json.Marshal(handlers.PostTransactionReverse{Amount: 300})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/transaction/%s/reverse", transactionID), bytes.NewBuffer(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got ledger.Transaction
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, transactionID, got.ReversalOf)
+
+	balance, err := user.GetBalanceByID(tests.Context(), test.MasterDB, transactionUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(700), balance)
+}
+
+func postTransactionReverseTwice(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/transaction/%s/reverse", transactionID), bytes.NewBufferString("{}"))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusConflict, w.Code, http.StatusText(w.Code))
+}
+
+func postTransactionReverseUnauthorized(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/transaction/%s/reverse", transactionID), bytes.NewBufferString("{}"))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, http.StatusText(w.Code))
+}
+
+// Captures, payouts and provider debits are undone through their hold, bet
+// or round, never by reversing them.
+func postTransactionReverseNotReversible(t *testing.T) {
+	ctx := tests.Context()
+
+	userID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Sam"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, 1000)
+	assert.NoError(t, err)
+
+	h, err := user.CreateHold(ctx, test.MasterDB, userID, 100, 0, "")
+	assert.NoError(t, err)
+	_, err = user.CaptureHold(ctx, test.MasterDB, h.ID, 0)
+	assert.NoError(t, err)
+
+	b, err := user.PlaceBet(ctx, test.MasterDB, user.Bet{UserID: userID, Market: "reverse", Selection: "home", Stake: 100, Odds: 200})
+	assert.NoError(t, err)
+	_, err = user.SettleBet(ctx, test.MasterDB, b.ID, user.BetWin)
+	assert.NoError(t, err)
+
+	_, err = user.ProcessProviderTxn(ctx, test.MasterDB, user.ProviderTxn{
+		Provider:      "reverse",
+		TransactionID: "reverse-debit",
+		RoundID:       "reverse-round",
+		UserID:        userID,
+		Type:          user.ProviderDebit,
+		Amount:        100,
+	})
+	assert.NoError(t, err)
+
+	ts, err := user.ListTransactionsByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+
+	reversed := map[string]bool{}
+	for _, tx := range ts {
+		switch tx.Type {
+		case ledger.TypeCapture, ledger.TypePayout, ledger.TypeDebit:
+		default:
+			continue
+		}
+		reversed[tx.Type] = true
+
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/transaction/%s/reverse", tx.ID), bytes.NewBufferString("{}"))
+		r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusConflict, w.Code, tx.Type)
+	}
+	assert.Equal(t, map[string]bool{ledger.TypeCapture: true, ledger.TypePayout: true, ledger.TypeDebit: true}, reversed)
+}
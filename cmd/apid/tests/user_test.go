@@ -7,10 +7,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
 	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/risk"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
 	"github.com/timurguseynov/go-wallet-api/internal/user"
 )
 
@@ -18,21 +22,39 @@ var (
 	userID         string
 	depositAmount  int64 = 10000
 	withdrawAmount int64 = 5000
+	transferAmount int64 = 1000
 )
 
 func RunTestUser(t *testing.T) {
 	t.Run("postUserCreate", postUserCreate)
+	t.Run("postUserCreateValidateName", postUserCreateValidateName)
+	t.Run("postUserCreateEmailTaken", postUserCreateEmailTaken)
+	t.Run("getUser", getUser)
+	t.Run("getUserByEmail", getUserByEmail)
+	t.Run("putUser", putUser)
+	t.Run("getUsers", getUsers)
+	t.Run("deleteUser", deleteUser)
 	t.Run("postUserDeposit", postUserDeposit)
 	t.Run("postUserDepositValidateAmount", postUserDepositValidateInputAmount)
 	t.Run("postUserWithdraw", postUserWithdraw)
 	t.Run("postUserWithdrawInsufficientFunds", postUserWithdrawInsufficientFunds)
 	t.Run("postUserWithdrawValidateAmount", postUserWithdrawValidateInputAmount)
 	t.Run("getUserBalance", getUserBalance)
+	t.Run("getUserBalanceNotFound", getUserBalanceNotFound)
+	t.Run("getUserBalanceAt", getUserBalanceAt)
+	t.Run("getUserBalanceRange", getUserBalanceRange)
+	t.Run("getUserBalanceRangeValidate", getUserBalanceRangeValidate)
+	t.Run("postUserDepositNotFound", postUserDepositNotFound)
+	t.Run("postUserTransfer", postUserTransfer)
+	t.Run("postUserTransferInsufficientFunds", postUserTransferInsufficientFunds)
+	t.Run("putUserStatus", putUserStatus)
+	t.Run("postUserWithdrawFrozen", postUserWithdrawFrozen)
 }
 
 func postUserCreate(t *testing.T) {
 	u := user.User{
-		Name: "Alex",
+		Name:  "Alex",
+		Email: "alex@example.com",
 	}
 	body, err := json.Marshal(u)
 	assert.NoError(t, err)
@@ -60,10 +82,12 @@ func postUserDeposit(t *testing.T) {
 	w := httptest.NewRecorder()
 	a.ServeHTTP(w, r)
 	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
-	var got bool
+	var got ledger.Transaction
 	err = json.NewDecoder(w.Body).Decode(&got)
 	assert.NoError(t, err)
-	assert.True(t, got)
+	assert.Equal(t, ledger.TypeDeposit, got.Type)
+	assert.Equal(t, int64(depositAmount), got.Amount)
+	assert.Equal(t, risk.Allow, got.Risk)
 }
 
 func postUserDepositValidateInputAmount(t *testing.T) {
@@ -99,10 +123,11 @@ func postUserWithdraw(t *testing.T) {
 	w := httptest.NewRecorder()
 	a.ServeHTTP(w, r)
 	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
-	var got bool
+	var got ledger.Transaction
 	err = json.NewDecoder(w.Body).Decode(&got)
 	assert.NoError(t, err)
-	assert.True(t, got)
+	assert.Equal(t, withdrawAmount, got.Amount)
+	assert.Equal(t, int64(0), got.Fee)
 }
 
 func postUserWithdrawInsufficientFunds(t *testing.T) {
@@ -155,3 +180,266 @@ func getUserBalance(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, depositAmount-withdrawAmount, got.Balance)
 }
+
+func getUserBalanceAt(t *testing.T) {
+	past := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/balance/%s?at=%s", userID, past), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got handlers.GetUserBalanceAt
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), got.Balance, "user didn't exist an hour ago")
+
+	now := time.Now().UTC().Add(time.Second).Format(time.RFC3339)
+	r = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/balance/%s?at=%s", userID, now), nil)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, depositAmount-withdrawAmount, got.Balance)
+}
+
+func getUserBalanceRange(t *testing.T) {
+	from := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/balance/%s/range?from=%s", userID, from), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got ledger.Range
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), got.Opening)
+	assert.Equal(t, depositAmount-withdrawAmount, got.Closing)
+	if assert.Len(t, got.Movements, 2) {
+		assert.Equal(t, depositAmount, got.Movements[0].Amount)
+		assert.Equal(t, -withdrawAmount, got.Movements[1].Amount)
+	}
+}
+
+func getUserBalanceRangeValidate(t *testing.T) {
+	from := time.Now().UTC().Format(time.RFC3339)
+	to := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/wallet/balance/%s/range?from=%s&to=%s", userID, from, to), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code, http.StatusText(w.Code))
+
+	var got rest.JSONError
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, "to", got.Fields[0].Fld)
+}
+
+func postUserTransfer(t *testing.T) {
+	toID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "John"})
+	assert.NoError(t, err)
+
+	userTransfer := handlers.PostUserTransfer{
+		ID:     userID,
+		To:     toID,
+		Amount: transferAmount,
+	}
+	body, err := json.Marshal(userTransfer)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/transfer", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	balance, err := user.GetBalanceByID(tests.Context(), test.MasterDB, toID)
+	assert.NoError(t, err)
+	assert.Equal(t, transferAmount, balance)
+}
+
+func postUserTransferInsufficientFunds(t *testing.T) {
+	toID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "John"})
+	assert.NoError(t, err)
+
+	userTransfer := handlers.PostUserTransfer{
+		ID:     userID,
+		To:     toID,
+		Amount: depositAmount,
+	}
+	body, err := json.Marshal(userTransfer)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/transfer", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code, http.StatusText(w.Code))
+}
+
+func putUserStatus(t *testing.T) {
+	userStatus := handlers.PutUserStatus{
+		Status: user.StatusFrozen,
+	}
+	body, err := json.Marshal(userStatus)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/admin/user/%s/status", userID), bytes.NewBuffer(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+}
+
+func postUserWithdrawFrozen(t *testing.T) {
+	userAmount := handlers.PostUserAmount{
+		ID:     userID,
+		Amount: 1,
+	}
+	body, err := json.Marshal(userAmount)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/withdraw", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusConflict, w.Code, http.StatusText(w.Code))
+
+	var got rest.JSONError
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ErrAccountFrozen.Error(), got.Error)
+}
+
+func postUserCreateValidateName(t *testing.T) {
+	body, err := json.Marshal(user.User{Email: "nameless@example.com"})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/create", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code, http.StatusText(w.Code))
+
+	var got rest.JSONError
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, "name", got.Fields[0].Fld)
+	assert.Equal(t, "cannot be blank", got.Fields[0].Err)
+}
+
+func postUserCreateEmailTaken(t *testing.T) {
+	body, err := json.Marshal(user.User{Name: "Alex", Email: "ALEX@example.com"})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/create", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusConflict, w.Code, http.StatusText(w.Code))
+}
+
+func getUser(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/user/%s", userID), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got user.User
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, got.ID)
+	assert.Equal(t, "Alex", got.Name)
+	assert.Equal(t, user.StatusActive, got.Status)
+}
+
+func getUserByEmail(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/user/email/alex@example.com", nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got user.User
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, got.ID)
+}
+
+func putUser(t *testing.T) {
+	body, err := json.Marshal(user.User{Name: "Alexander", Email: "alex@example.com", Country: "DE"})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/admin/user/%s", userID), bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, http.StatusText(w.Code))
+
+	r = httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/admin/user/%s", userID), bytes.NewBuffer(body))
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got user.User
+	err = json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, "Alexander", got.Name)
+	assert.Equal(t, "DE", got.Country)
+}
+
+func getUsers(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/user?q=alexander&limit=1", nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+
+	var got handlers.GetUsers
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got.Total)
+	assert.Equal(t, 1, len(got.Users))
+	assert.Equal(t, userID, got.Users[0].ID)
+}
+
+func deleteUser(t *testing.T) {
+	id, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "John"})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/admin/user/%s", id), nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, http.StatusText(w.Code))
+
+	r = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/admin/user/%s", id), nil)
+	r.Header.Set(rest.AuthorizationHeader, "Bearer "+adminToken)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code, http.StatusText(w.Code))
+
+	r = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/user/%s", id), nil)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code, http.StatusText(w.Code))
+}
+
+func getUserBalanceNotFound(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/wallet/balance/unknown", nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code, http.StatusText(w.Code))
+
+	var got rest.JSONError
+	err := json.NewDecoder(w.Body).Decode(&got)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ErrNotFound.Error(), got.Error)
+}
+
+func postUserDepositNotFound(t *testing.T) {
+	userAmount := handlers.PostUserAmount{
+		ID:     "unknown",
+		Amount: depositAmount,
+	}
+	body, err := json.Marshal(userAmount)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/wallet/deposit", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code, http.StatusText(w.Code))
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var (
+	legacyDeprecated = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	legacySunset     = time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func RunTestVersioning(t *testing.T) {
+	t.Run("versioned", getVersioned)
+	t.Run("legacy", getLegacy)
+	t.Run("legacyGroupMiddleware", postLegacyUnsigned)
+	t.Run("legacySpec", getLegacySpec)
+}
+
+func getVersioned(t *testing.T) {
+	userID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Ana"})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/user/"+userID, nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+	assert.Empty(t, w.Header().Get(rest.DeprecationHeader))
+	assert.Empty(t, w.Header().Get(rest.SunsetHeader))
+}
+
+func getLegacy(t *testing.T) {
+	userID, err := user.Insert(tests.Context(), test.MasterDB, user.User{Name: "Ana"})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/user/"+userID, nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, http.StatusText(w.Code))
+	assert.Equal(t, "@"+strconv.FormatInt(legacyDeprecated.Unix(), 10), w.Header().Get(rest.DeprecationHeader))
+	assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", w.Header().Get(rest.SunsetHeader))
+	assert.Equal(t, `</api/v1/user/`+userID+`>; rel="successor-version"`, w.Header().Get(rest.LinkHeader))
+
+	// Errors are marked too.
+	r = httptest.NewRequest(http.MethodGet, "/api/wallet/bet/missing", nil)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code, http.StatusText(w.Code))
+	assert.NotEmpty(t, w.Header().Get(rest.DeprecationHeader))
+	assert.Equal(t, `</api/v1/wallet/bet/missing>; rel="successor-version"`, w.Header().Get(rest.LinkHeader))
+}
+
+// postLegacyUnsigned makes sure nested groups keep their middleware under
+// both prefixes.
+func postLegacyUnsigned(t *testing.T) {
+	body, err := json.Marshal(handlers.PostSeamlessBalance{UserID: "anyone"})
+	assert.NoError(t, err)
+
+	for _, path := range []string{"/api/v1/seamless/balance", "/api/seamless/balance"} {
+		r := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+	}
+}
+
+func getLegacySpec(t *testing.T) {
+	spec := a.OpenAPI("", "")
+
+	if op := spec.Paths["/api/v1/wallet/deposit"]["post"]; assert.NotNil(t, op) {
+		assert.False(t, op.Deprecated)
+	}
+	if op := spec.Paths["/api/wallet/deposit"]["post"]; assert.NotNil(t, op) {
+		assert.True(t, op.Deprecated)
+		assert.Equal(t, "Deposit into a wallet", op.Summary)
+	}
+}
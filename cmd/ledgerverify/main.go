@@ -0,0 +1,86 @@
+// This program verifies a ledger snapshot taken from the apid admin API. It
+// walks the hash chain and the signed checkpoints and reports the first link
+// that doesn't hold.
+//
+// Usage:
+//
+//	ledgerverify -pubkey base64 [snapshot.json]
+//	ledgerverify -insecure [snapshot.json]
+//
+// The snapshot is read from stdin when no file is given. With -insecure
+// instead of -pubkey the checkpoints are checked against the key they carry,
+// which only proves the snapshot is consistent with itself.
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ledgerverify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pubkey := flags.String("pubkey", "", "base64 ed25519 public key the checkpoints must be signed with")
+	insecure := flags.Bool("insecure", false, "check the checkpoints against the key in the snapshot instead of -pubkey")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *pubkey == "" && !*insecure {
+		fmt.Fprintln(stderr, "ledgerverify : -pubkey is required, pass -insecure to trust the key in the snapshot")
+		return 2
+	}
+
+	var pub ed25519.PublicKey
+	if *pubkey != "" {
+		b, err := base64.StdEncoding.DecodeString(*pubkey)
+		if err != nil || len(b) != ed25519.PublicKeySize {
+			fmt.Fprintln(stderr, "ledgerverify : invalid public key")
+			return 2
+		}
+		pub = ed25519.PublicKey(b)
+	}
+
+	in := stdin
+	if flags.NArg() > 0 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(stderr, "ledgerverify : %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var s ledger.Snapshot
+	if err := json.NewDecoder(in).Decode(&s); err != nil {
+		fmt.Fprintf(stderr, "ledgerverify : couldn't read snapshot : %v\n", err)
+		return 2
+	}
+
+	if b := ledger.Verify(s, pub); b != nil {
+		fmt.Fprintf(stdout, "BROKEN at seq %d", b.Seq)
+		if b.TransactionID != "" {
+			fmt.Fprintf(stdout, " (transaction %s)", b.TransactionID)
+		}
+		fmt.Fprintf(stdout, " : %s\n", b.Reason)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "OK : %d transactions, %d checkpoints\n", len(s.Transactions), len(s.Checkpoints))
+	if pub == nil && len(s.Checkpoints) > 0 {
+		fmt.Fprintln(stdout, "checkpoints were verified with the key in the snapshot, pass -pubkey instead of -insecure to check who signed them")
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var (
+	ctx      context.Context
+	key      ed25519.PrivateKey
+	snapshot ledger.Snapshot
+)
+
+func TestVerify(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	var err error
+	key, err = ledger.GenerateSigningKey(rand.Reader)
+	assert.NoError(t, err)
+
+	// A chain of a few transactions, signed at its end.
+	userID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Val"})
+	assert.NoError(t, err)
+	for _, amount := range []int64{100, 200, 300} {
+		_, err = user.DepositByID(ctx, test.MasterDB, userID, amount)
+		assert.NoError(t, err)
+	}
+	_, err = user.WithdrawByID(ctx, test.MasterDB, userID, 50)
+	assert.NoError(t, err)
+
+	c, err := ledger.CreateCheckpoint(ctx, test.MasterDB, key, time.Now())
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+
+	s, err := ledger.TakeSnapshot(ctx, test.MasterDB)
+	assert.NoError(t, err)
+	snapshot = *s
+
+	t.Run("verifyOK", verifyOK)
+	t.Run("verifyMissingPublicKey", verifyMissingPublicKey)
+	t.Run("verifyPublicKey", verifyPublicKey)
+	t.Run("verifyOtherPublicKey", verifyOtherPublicKey)
+	t.Run("verifyEditedAmount", verifyEditedAmount)
+	t.Run("verifyEditedHash", verifyEditedHash)
+}
+
+// verify runs ledgerverify on s with args and returns its exit code and
+// output.
+func verify(t *testing.T, s ledger.Snapshot, args ...string) (int, string) {
+	b, err := json.Marshal(s)
+	assert.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, bytes.NewReader(b), &stdout, &stderr)
+	assert.Empty(t, stderr.String())
+
+	return code, stdout.String()
+}
+
+// tampered returns a copy of the snapshot whose transactions can be edited.
+func tampered() ledger.Snapshot {
+	s := snapshot
+	s.Transactions = append([]ledger.Transaction(nil), snapshot.Transactions...)
+	return s
+}
+
+func publicKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+func verifyOK(t *testing.T) {
+	code, out := verify(t, snapshot, "-insecure")
+	assert.Equal(t, 0, code, out)
+	assert.Contains(t, out, fmt.Sprintf("OK : %d transactions, 1 checkpoints", len(snapshot.Transactions)))
+	assert.Contains(t, out, "pass -pubkey")
+}
+
+func verifyMissingPublicKey(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run(nil, bytes.NewReader(nil), &stdout, &stderr)
+	assert.Equal(t, 2, code)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "-pubkey is required")
+}
+
+func verifyPublicKey(t *testing.T) {
+	code, out := verify(t, snapshot, "-pubkey", publicKey(key))
+	assert.Equal(t, 0, code, out)
+	assert.NotContains(t, out, "pass -pubkey")
+}
+
+func verifyOtherPublicKey(t *testing.T) {
+	other, err := ledger.GenerateSigningKey(rand.Reader)
+	assert.NoError(t, err)
+
+	code, out := verify(t, snapshot, "-pubkey", publicKey(other))
+	assert.Equal(t, 1, code, out)
+	assert.Contains(t, out, "checkpoint signature doesn't verify")
+}
+
+func verifyEditedAmount(t *testing.T) {
+	s := tampered()
+	s.Transactions[1].Amount = 2000
+
+	code, out := verify(t, s, "-insecure")
+	assert.Equal(t, 1, code, out)
+	assert.Equal(t, "BROKEN at seq 2 (transaction "+s.Transactions[1].ID+") : hash doesn't match content\n", out)
+}
+
+func verifyEditedHash(t *testing.T) {
+	// Rehashing the edited transaction breaks the link to the next one.
+	s := tampered()
+	s.Transactions[1].Amount = 2000
+	s.Transactions[1].Hash = ledger.Hash(s.Transactions[1])
+
+	code, out := verify(t, s, "-insecure")
+	assert.Equal(t, 1, code, out)
+	assert.Equal(t, "BROKEN at seq 3 (transaction "+s.Transactions[2].ID+") : previous hash doesn't match\n", out)
+}
@@ -0,0 +1,158 @@
+// This program imports users with their opening balances into a running
+// apid through the admin API and follows the import job until it's done.
+//
+// Usage:
+//
+//	walletimport [-addr url] [-token token] [-format csv|ndjson] [-dry-run] users.csv
+//
+// The token is the bearer token of an admin, WALLET_API_TOKEN by default.
+// The format is taken from the file extension when it isn't given. Rows
+// that can't be imported are listed with their line numbers, the exit code
+// is 1 when there are any.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timurguseynov/go-wallet-api/internal/importer"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+// extFormats maps file extensions to the format of the file.
+var extFormats = map[string]string{
+	".csv":    importer.FormatCSV,
+	".ndjson": importer.FormatNDJSON,
+	".jsonl":  importer.FormatNDJSON,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("walletimport", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", "http://127.0.0.1:3000", "base URL of the apid to import into")
+	token := flags.String("token", os.Getenv("WALLET_API_TOKEN"), "bearer token of an admin")
+	format := flags.String("format", "", "csv or ndjson, taken from the file extension by default")
+	dryRun := flags.Bool("dry-run", false, "check every row without committing anything")
+	interval := flags.Duration("interval", time.Second, "how often to poll the import job")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "walletimport : exactly one file to import is required")
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = extFormats[strings.ToLower(filepath.Ext(path))]
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "walletimport : %v\n", err)
+		return 2
+	}
+	defer f.Close()
+
+	q := url.Values{}
+	q.Set("format", *format)
+	q.Set("dry_run", strconv.FormatBool(*dryRun))
+
+	c := client{token: *token}
+
+	var job importer.Job
+	resp, err := c.do(http.MethodPost, *addr+"/api/v1/admin/import?"+q.Encode(), f)
+	if err := decode(resp, err, http.StatusAccepted, &job); err != nil {
+		fmt.Fprintf(stderr, "walletimport : couldn't start import : %v\n", err)
+		return 2
+	}
+
+	processed := -1
+	for {
+		if job.Processed != processed {
+			processed = job.Processed
+			fmt.Fprintf(stdout, "%d/%d rows : %d imported, %d failed\n", job.Processed, job.Total, job.Imported, job.Failed)
+		}
+		if job.Status != importer.StatusRunning {
+			break
+		}
+
+		time.Sleep(*interval)
+
+		resp, err := c.do(http.MethodGet, *addr+"/api/v1/admin/import/"+job.ID, nil)
+		if err := decode(resp, err, http.StatusOK, &job); err != nil {
+			fmt.Fprintf(stderr, "walletimport : couldn't get import job %s : %v\n", job.ID, err)
+			return 2
+		}
+	}
+
+	for _, e := range job.Errors {
+		if e.Field != "" {
+			fmt.Fprintf(stdout, "line %d : %s : %s\n", e.Line, e.Field, e.Error)
+			continue
+		}
+		fmt.Fprintf(stdout, "line %d : %s\n", e.Line, e.Error)
+	}
+
+	if job.Status == importer.StatusFailed {
+		fmt.Fprintf(stdout, "FAILED : %s\n", job.Error)
+		return 1
+	}
+	if job.DryRun {
+		fmt.Fprintln(stdout, "dry run, nothing was committed")
+	}
+	if job.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// client calls the admin API as the admin the token belongs to.
+type client struct {
+	token string
+}
+
+func (c client) do(method, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(rest.AuthorizationHeader, "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	return http.DefaultClient.Do(req)
+}
+
+// decode reads the JSON body of resp into v when it has the status
+// expected, and the API error otherwise.
+func decode(resp *http.Response, err error, status int, v interface{}) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		var jerr rest.JSONError
+		if err := json.NewDecoder(resp.Body).Decode(&jerr); err != nil || jerr.Error == "" {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return fmt.Errorf("%s : %s", resp.Status, jerr.Error)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
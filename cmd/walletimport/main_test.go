@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/cmd/apid/handlers"
+	"github.com/timurguseynov/go-wallet-api/config"
+	"github.com/timurguseynov/go-wallet-api/internal/health"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+const adminToken = "admin-token"
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var (
+	ctx context.Context
+	srv *httptest.Server
+)
+
+func TestImport(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	tokens, err := rest.ParseTokens("ops:admin:" + adminToken)
+	assert.NoError(t, err)
+
+	srv = httptest.NewServer(handlers.API(test.MasterDB, config.Config{}, tokens, health.New(test.MasterDB)))
+	defer srv.Close()
+
+	t.Run("importDryRun", importDryRun)
+	t.Run("importRowErrors", importRowErrors)
+	t.Run("importUnauthorized", importUnauthorized)
+}
+
+// walletimport writes contents to a file called name and runs walletimport
+// on it against srv with args. It returns the exit code, stdout and stderr.
+func walletimport(t *testing.T, name, contents string, args ...string) (int, string, string) {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(contents), 0600)
+	assert.NoError(t, err)
+
+	args = append([]string{"-addr", srv.URL, "-interval", "10ms"}, args...)
+	args = append(args, path)
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func importDryRun(t *testing.T) {
+	code, out, errOut := walletimport(t, "users.csv", "name,email,balance\nBo,bo@walletimport.test,50\n",
+		"-token", adminToken, "-dry-run")
+	assert.Equal(t, 0, code, errOut)
+	assert.Contains(t, out, "1/1 rows : 1 imported, 0 failed\n")
+	assert.Contains(t, out, "dry run, nothing was committed\n")
+
+	_, err := user.GetByEmail(ctx, test.MasterDB, "bo@walletimport.test")
+	assert.Equal(t, user.ErrNotFound, err)
+}
+
+func importRowErrors(t *testing.T) {
+	rows := `{"name":"Ana","email":"ana@walletimport.test","balance":700}
+{"name":"","email":"blank@walletimport.test"}
+`
+	code, out, errOut := walletimport(t, "users.ndjson", rows, "-token", adminToken)
+	assert.Equal(t, 1, code, errOut)
+	assert.Contains(t, out, "2/2 rows : 1 imported, 1 failed\n")
+	assert.Contains(t, out, "line 2 : name : cannot be blank\n")
+	assert.NotContains(t, out, "dry run")
+
+	u, err := user.GetByEmail(ctx, test.MasterDB, "ana@walletimport.test")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(700), u.Balance)
+}
+
+func importUnauthorized(t *testing.T) {
+	code, out, errOut := walletimport(t, "users.csv", "name,email,balance\nCy,cy@walletimport.test,50\n",
+		"-token", "wrong")
+	assert.Equal(t, 2, code)
+	assert.Empty(t, out)
+	assert.Contains(t, errOut, "couldn't start import : 401 Unauthorized")
+}
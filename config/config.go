@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/joho/godotenv"
 
 	"github.com/kelseyhightower/envconfig"
@@ -8,8 +10,64 @@ import (
 
 type Config struct {
 	REST struct {
-		Host string `default:"127.0.0.1" envconfig:"HOST"`
-		Port string `default:"3000" envconfig:"PORT"`
+		Host       string        `default:"127.0.0.1" envconfig:"HOST"`
+		Port       string        `default:"3000" envconfig:"PORT"`
+		DrainDelay time.Duration `default:"5s" envconfig:"DRAIN_DELAY"`
+	}
+	Auth struct {
+		Tokens string `envconfig:"TOKENS"`
+	}
+	Legacy struct {
+		Deprecated time.Time `default:"2026-10-19T00:00:00Z" envconfig:"DEPRECATED"`
+		Sunset     time.Time `default:"2027-04-19T00:00:00Z" envconfig:"SUNSET"`
+	}
+	Log struct {
+		Level  string `default:"info" envconfig:"LEVEL"`
+		Format string `default:"text" envconfig:"FORMAT"`
+	}
+	Trace struct {
+		Exporter string `default:"none" envconfig:"EXPORTER"`
+		Endpoint string `envconfig:"ENDPOINT"`
+		Insecure bool   `envconfig:"INSECURE"`
+		File     string `envconfig:"FILE"`
+	}
+	Ledger struct {
+		SigningKey         string        `envconfig:"SIGNING_KEY"`
+		CheckpointInterval time.Duration `default:"1m" envconfig:"CHECKPOINT_INTERVAL"`
+	}
+	Limits struct {
+		MinAmount       int64 `default:"10" envconfig:"MIN_AMOUNT"`
+		MaxAmount       int64 `envconfig:"MAX_AMOUNT"`
+		DailyDeposit    int64 `envconfig:"DAILY_DEPOSIT"`
+		DailyWithdraw   int64 `envconfig:"DAILY_WITHDRAW"`
+		DailyCount      int64 `envconfig:"DAILY_COUNT"`
+		WeeklyDeposit   int64 `envconfig:"WEEKLY_DEPOSIT"`
+		WeeklyWithdraw  int64 `envconfig:"WEEKLY_WITHDRAW"`
+		WeeklyCount     int64 `envconfig:"WEEKLY_COUNT"`
+		MonthlyDeposit  int64 `envconfig:"MONTHLY_DEPOSIT"`
+		MonthlyWithdraw int64 `envconfig:"MONTHLY_WITHDRAW"`
+		MonthlyCount    int64 `envconfig:"MONTHLY_COUNT"`
+	}
+	Holds struct {
+		ExpireInterval time.Duration `default:"10s" envconfig:"EXPIRE_INTERVAL"`
+	}
+	Bonus struct {
+		ExpireInterval time.Duration `default:"1m" envconfig:"EXPIRE_INTERVAL"`
+	}
+	Audit struct {
+		Retention     time.Duration `default:"2160h" envconfig:"RETENTION"`
+		PruneInterval time.Duration `default:"1h" envconfig:"PRUNE_INTERVAL"`
+	}
+	Reconcile struct {
+		Interval time.Duration `default:"1h" envconfig:"INTERVAL"`
+	}
+	Risk struct {
+		Rules          string        `envconfig:"RULES"`
+		ReloadInterval time.Duration `default:"10s" envconfig:"RELOAD_INTERVAL"`
+	}
+	Seamless struct {
+		Secret          string        `envconfig:"SECRET"`
+		SignatureWindow time.Duration `default:"5m" envconfig:"SIGNATURE_WINDOW"`
 	}
 }
 
@@ -0,0 +1,274 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+)
+
+// maxBody is the most of a request body an entry keeps.
+const maxBody = 64 << 10
+
+// Redacted replaces the values of sensitive fields in recorded bodies.
+const Redacted = "[redacted]"
+
+// sensitive lists the parts of field names whose values are never recorded.
+var sensitive = []string{"password", "secret", "token", "signature", "key", "card", "cvv"}
+
+// Anonymous is the actor of requests that weren't authenticated.
+const Anonymous = "anonymous"
+
+// Entry is a state changing request. Actor is the principal the request was
+// authenticated as, Anonymous when it wasn't. RemoteAddr is the address the
+// request came from, a proxy's when there's one in between.
+type Entry struct {
+	ID         string          `json:"id"`
+	Seq        uint64          `json:"seq"`
+	TraceID    string          `json:"trace_id"`
+	Actor      string          `json:"actor"`
+	AuthMethod string          `json:"auth_method"`
+	RemoteAddr string          `json:"remote_addr"`
+	Method     string          `json:"method"`
+	Route      string          `json:"route"`
+	Path       string          `json:"path"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	Status     int             `json:"status"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Query filters the entries returned by List. Zero values match anything.
+type Query struct {
+	Actor  string
+	Method string
+	Route  string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// Middleware records every request with a method that changes state into
+// the audit log of dbConn once it's been handled.
+func Middleware(dbConn *db.DB) rest.Middleware {
+	return func(next rest.Handler) rest.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				return next(ctx, w, r, params)
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+			if err != nil {
+				return errors.Wrap(err, "")
+			}
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+
+			err = next(ctx, w, r, params)
+
+			v := ctx.Value(rest.KeyValues).(*rest.Values)
+
+			e := Entry{
+				TraceID:    v.TraceID,
+				Actor:      v.Actor,
+				AuthMethod: v.AuthMethod,
+				RemoteAddr: r.RemoteAddr,
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Body:       sanitize(body),
+				Status:     v.StatusCode,
+				CreatedAt:  v.Now,
+			}
+			if e.AuthMethod == "" {
+				e.AuthMethod = rest.AuthNone
+				e.Actor = Anonymous
+			}
+			if route := mux.CurrentRoute(r); route != nil {
+				e.Route, _ = route.GetPathTemplate()
+			}
+
+			if rerr := Record(ctx, dbConn, e); rerr != nil {
+				rest.Logger().ErrorContext(ctx, "couldn't record audit entry", "error", rerr)
+			}
+
+			return err
+		}
+	}
+}
+
+// Record appends e to the audit log. ID and Seq are assigned here.
+func Record(ctx context.Context, dbConn *db.DB, e Entry) error {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	raw, err := txn.Last("audit", "seq")
+	if err != nil {
+		return errors.Wrap(err, "txn.Last")
+	}
+	if last, ok := raw.(Entry); ok {
+		e.Seq = last.Seq + 1
+	} else {
+		e.Seq = 1
+	}
+	e.ID = uuid.New().String()
+
+	if err := txn.Insert("audit", e); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	txn.Commit()
+
+	return nil
+}
+
+// Prune deletes the entries created before before, oldest first, and returns
+// how many there were. It stops at the first entry that isn't that old and
+// always keeps the newest one, so Seq keeps counting up.
+func Prune(ctx context.Context, dbConn *db.DB, before time.Time) (int, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	it, err := txn.Get("audit", "seq")
+	if err != nil {
+		return 0, errors.Wrap(err, "txn.Get")
+	}
+
+	var old []Entry
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		e, ok := obj.(Entry)
+		if !ok {
+			return 0, errors.New("couldn't type assert audit entry")
+		}
+		if !e.CreatedAt.Before(before) {
+			break
+		}
+		old = append(old, e)
+	}
+
+	// Record numbers the next entry after the newest one.
+	raw, err := txn.Last("audit", "seq")
+	if err != nil {
+		return 0, errors.Wrap(err, "txn.Last")
+	}
+	if last, ok := raw.(Entry); ok && len(old) > 0 && old[len(old)-1].Seq == last.Seq {
+		old = old[:len(old)-1]
+	}
+
+	for _, e := range old {
+		if err := txn.Delete("audit", e); err != nil {
+			return 0, errors.Wrap(err, "txn.Delete")
+		}
+	}
+
+	txn.Commit()
+
+	return len(old), nil
+}
+
+// List returns the page of entries matching q, newest first, and the number
+// of entries matching it in total.
+func List(ctx context.Context, dbConn *db.DB, q Query) ([]Entry, int, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	it, err := txn.GetReverse("audit", "seq")
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "txn.GetReverse")
+	}
+
+	matched := []Entry{}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		e, ok := obj.(Entry)
+		if !ok {
+			return nil, 0, errors.New("couldn't type assert audit entry")
+		}
+		if q.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+
+	total := len(matched)
+	if q.Offset >= total {
+		return []Entry{}, total, nil
+	}
+	end := total
+	if q.Limit > 0 && q.Offset+q.Limit < total {
+		end = q.Offset + q.Limit
+	}
+
+	return matched[q.Offset:end], total, nil
+}
+
+func (q Query) matches(e Entry) bool {
+	switch {
+	case q.Actor != "" && q.Actor != e.Actor:
+		return false
+	case q.Method != "" && !strings.EqualFold(q.Method, e.Method):
+		return false
+	case q.Route != "" && q.Route != e.Route:
+		return false
+	case !q.Since.IsZero() && e.CreatedAt.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !e.CreatedAt.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// sanitize returns the JSON body with the values of sensitive fields
+// redacted. Bodies that aren't JSON or are too long aren't kept.
+func sanitize(body []byte) json.RawMessage {
+	if len(body) == 0 || len(body) > maxBody {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil
+	}
+
+	b, err := json.Marshal(redact(v))
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
+
+func redact(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if isSensitive(k) {
+				t[k] = Redacted
+				continue
+			}
+			t[k] = redact(val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = redact(val)
+		}
+	}
+	return v
+}
+
+func isSensitive(field string) bool {
+	field = strings.ToLower(field)
+	for _, s := range sensitive {
+		if strings.Contains(field, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/audit"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var ctx context.Context
+
+func TestAudit(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	t.Run("auditList", auditList)
+	t.Run("auditPrune", auditPrune)
+}
+
+func auditList(t *testing.T) {
+	now := time.Now()
+	for i, actor := range []string{"alice", "bob", "alice"} {
+		err := audit.Record(ctx, test.MasterDB, audit.Entry{
+			Actor:     actor,
+			Method:    "POST",
+			Route:     "/api/wallet/deposit",
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		})
+		assert.NoError(t, err)
+	}
+
+	entries, total, err := audit.List(ctx, test.MasterDB, audit.Query{Actor: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, uint64(3), entries[0].Seq)
+	assert.Equal(t, uint64(1), entries[1].Seq)
+
+	entries, total, err = audit.List(ctx, test.MasterDB, audit.Query{Since: now.Add(time.Minute), Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "alice", entries[0].Actor)
+}
+
+func auditPrune(t *testing.T) {
+	// The entries of auditList are at most two minutes ahead of now.
+	n, err := audit.Prune(ctx, test.MasterDB, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = audit.Prune(ctx, test.MasterDB, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n, "the newest entry is kept")
+
+	err = audit.Record(ctx, test.MasterDB, audit.Entry{Actor: "carol", Method: "POST", CreatedAt: time.Now()})
+	assert.NoError(t, err)
+
+	entries, total, err := audit.List(ctx, test.MasterDB, audit.Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, uint64(4), entries[0].Seq)
+	assert.Equal(t, uint64(3), entries[1].Seq)
+}
@@ -4,8 +4,14 @@
 package db
 
 import (
+	"context"
+	"time"
+
 	"github.com/hashicorp/go-memdb"
 	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/metrics"
+	"github.com/timurguseynov/go-wallet-api/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type DB struct {
@@ -17,6 +23,250 @@ var schema = &memdb.DBSchema{
 	Tables: map[string]*memdb.TableSchema{
 		"user": &memdb.TableSchema{
 			Name: "user",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+				"email": &memdb.IndexSchema{
+					Name:         "email",
+					Unique:       true,
+					AllowMissing: true,
+					Indexer:      &memdb.StringFieldIndex{Field: "Email", Lowercase: true},
+				},
+			},
+		},
+		"transaction": &memdb.TableSchema{
+			Name: "transaction",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+				"seq": &memdb.IndexSchema{
+					Name:    "seq",
+					Unique:  true,
+					Indexer: &memdb.UintFieldIndex{Field: "Seq"},
+				},
+				"account": &memdb.IndexSchema{
+					Name:    "account",
+					Indexer: &memdb.StringSliceFieldIndex{Field: "Accounts"},
+				},
+				"user_created": &memdb.IndexSchema{
+					Name:         "user_created",
+					AllowMissing: true,
+					Indexer: &memdb.CompoundIndex{
+						Indexes: []memdb.Indexer{
+							&memdb.StringFieldIndex{Field: "UserID"},
+							&TimeFieldIndex{Field: "CreatedAt"},
+						},
+					},
+				},
+				"reversal_of": &memdb.IndexSchema{
+					Name:         "reversal_of",
+					AllowMissing: true,
+					Indexer:      &memdb.StringFieldIndex{Field: "ReversalOf"},
+				},
+			},
+		},
+		"checkpoint": &memdb.TableSchema{
+			Name: "checkpoint",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "Hash"},
+				},
+				"seq": &memdb.IndexSchema{
+					Name:    "seq",
+					Unique:  true,
+					Indexer: &memdb.UintFieldIndex{Field: "Seq"},
+				},
+			},
+		},
+		"account": &memdb.TableSchema{
+			Name: "account",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+			},
+		},
+		"entry": &memdb.TableSchema{
+			Name: "entry",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:   "id",
+					Unique: true,
+					Indexer: &memdb.CompoundIndex{
+						Indexes: []memdb.Indexer{
+							&memdb.StringFieldIndex{Field: "AccountID"},
+							&memdb.UintFieldIndex{Field: "Seq"},
+						},
+					},
+				},
+			},
+		},
+		"balance_checkpoint": &memdb.TableSchema{
+			Name: "balance_checkpoint",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:   "id",
+					Unique: true,
+					Indexer: &memdb.CompoundIndex{
+						Indexes: []memdb.Indexer{
+							&memdb.StringFieldIndex{Field: "AccountID"},
+							&memdb.UintFieldIndex{Field: "Seq"},
+						},
+					},
+				},
+			},
+		},
+		"import_job": &memdb.TableSchema{
+			Name: "import_job",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+			},
+		},
+		"limit": &memdb.TableSchema{
+			Name: "limit",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+			},
+		},
+		"fee": &memdb.TableSchema{
+			Name: "fee",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+			},
+		},
+		"hold": &memdb.TableSchema{
+			Name: "hold",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+				"user_id": &memdb.IndexSchema{
+					Name:    "user_id",
+					Indexer: &memdb.StringFieldIndex{Field: "UserID"},
+				},
+				"status": &memdb.IndexSchema{
+					Name:    "status",
+					Indexer: &memdb.StringFieldIndex{Field: "Status"},
+				},
+			},
+		},
+		"audit": &memdb.TableSchema{
+			Name: "audit",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+				"seq": &memdb.IndexSchema{
+					Name:    "seq",
+					Unique:  true,
+					Indexer: &memdb.UintFieldIndex{Field: "Seq"},
+				},
+			},
+		},
+		"bet": &memdb.TableSchema{
+			Name: "bet",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+				"market": &memdb.IndexSchema{
+					Name:    "market",
+					Indexer: &memdb.StringFieldIndex{Field: "Market"},
+				},
+				"settle_seq": &memdb.IndexSchema{
+					Name:    "settle_seq",
+					Indexer: &memdb.UintFieldIndex{Field: "SettleSeq"},
+				},
+			},
+		},
+		"bonus": &memdb.TableSchema{
+			Name: "bonus",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+				"user_id": &memdb.IndexSchema{
+					Name:    "user_id",
+					Indexer: &memdb.StringFieldIndex{Field: "UserID"},
+				},
+				"status": &memdb.IndexSchema{
+					Name:    "status",
+					Indexer: &memdb.StringFieldIndex{Field: "Status"},
+				},
+			},
+		},
+		"provider_txn": &memdb.TableSchema{
+			Name: "provider_txn",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:   "id",
+					Unique: true,
+					Indexer: &memdb.CompoundIndex{
+						Indexes: []memdb.Indexer{
+							&memdb.StringFieldIndex{Field: "Provider"},
+							&memdb.StringFieldIndex{Field: "TransactionID"},
+						},
+					},
+				},
+				"rollback_of": &memdb.IndexSchema{
+					Name:         "rollback_of",
+					AllowMissing: true,
+					Indexer: &memdb.CompoundIndex{
+						Indexes: []memdb.Indexer{
+							&memdb.StringFieldIndex{Field: "Provider"},
+							&memdb.StringFieldIndex{Field: "RollbackOf"},
+						},
+					},
+				},
+			},
+		},
+		"reconciliation": &memdb.TableSchema{
+			Name: "reconciliation",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": &memdb.IndexSchema{
+					Name:    "id",
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: "ID"},
+				},
+				"seq": &memdb.IndexSchema{
+					Name:    "seq",
+					Unique:  true,
+					Indexer: &memdb.UintFieldIndex{Field: "Seq"},
+				},
+			},
+		},
+		"rule": &memdb.TableSchema{
+			Name: "rule",
 			Indexes: map[string]*memdb.IndexSchema{
 				"id": &memdb.IndexSchema{
 					Name:    "id",
@@ -36,3 +286,21 @@ func NewDB() (*DB, error) {
 
 	return &DB{db}, nil
 }
+
+// Txn starts a transaction. Write transactions hold the writer lock until
+// they end, committed ones report how long that was and are traced as a span
+// of the one in ctx.
+func (db *DB) Txn(ctx context.Context, write bool) *memdb.Txn {
+	txn := db.MemDB.Txn(write)
+	if write {
+		start := time.Now()
+		txn.Defer(func() {
+			metrics.ObserveTxn(time.Since(start))
+
+			_, span := tracing.Start(ctx, "memdb.txn", trace.WithTimestamp(start))
+			span.End()
+		})
+	}
+
+	return txn
+}
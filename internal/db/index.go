@@ -0,0 +1,50 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// TimeFieldIndex indexes a time.Time field so the entries are ordered by it,
+// for range scans with LowerBound.
+type TimeFieldIndex struct {
+	Field string
+}
+
+func (t *TimeFieldIndex) FromObject(obj interface{}) (bool, []byte, error) {
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	fv := v.FieldByName(t.Field)
+	if !fv.IsValid() {
+		return false, nil, fmt.Errorf("field '%s' for %#v is invalid", t.Field, obj)
+	}
+
+	at, ok := fv.Interface().(time.Time)
+	if !ok {
+		return false, nil, fmt.Errorf("field '%s' for %#v is not a time.Time", t.Field, obj)
+	}
+
+	return true, encodeTime(at), nil
+}
+
+func (t *TimeFieldIndex) FromArgs(args ...interface{}) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("must provide only a single argument")
+	}
+
+	at, ok := args[0].(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("argument must be a time.Time: %#v", args[0])
+	}
+
+	return encodeTime(at), nil
+}
+
+// encodeTime encodes at so the bytes sort like the times, those before 1970
+// included.
+func encodeTime(at time.Time) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(at.UnixNano())^(1<<63))
+	return buf
+}
@@ -0,0 +1,174 @@
+package fee
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+)
+
+// AnyCurrency is the currency of schedules that apply to every currency
+// without a schedule of its own.
+const AnyCurrency = ""
+
+var (
+	ErrInvalidSchedule = errors.New("invalid fee schedule")
+)
+
+// Tier is the fee for amounts up to UpTo, zero meaning any amount. Rate is
+// in basis points of the amount, 150 is 1.5%.
+type Tier struct {
+	UpTo  int64 `json:"up_to,omitempty"`
+	Fixed int64 `json:"fixed,omitempty"`
+	Rate  int64 `json:"rate,omitempty"`
+}
+
+// Schedule is the fee charged for a transaction type in a currency. With
+// Tiers the first tier the amount fits in applies, otherwise Fixed plus Rate
+// basis points of the amount. The result is capped by Min and Max, zero
+// meaning no cap.
+type Schedule struct {
+	ID       string `json:"-"`
+	Op       string `json:"op"`
+	Currency string `json:"currency,omitempty"`
+	Fixed    int64  `json:"fixed,omitempty"`
+	Rate     int64  `json:"rate,omitempty"`
+	Tiers    []Tier `json:"tiers,omitempty"`
+	Min      int64  `json:"min,omitempty"`
+	Max      int64  `json:"max,omitempty"`
+}
+
+// Quote is the fee a transaction would be charged and what it costs in
+// total.
+type Quote struct {
+	Op       string `json:"op"`
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+	Fee      int64  `json:"fee"`
+	Total    int64  `json:"total"`
+}
+
+// Set stores s, replacing the schedule for the same op and currency.
+func Set(ctx context.Context, dbConn *db.DB, s Schedule) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+
+	s.ID = id(s.Op, s.Currency)
+
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	if err := txn.Insert("fee", s); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	txn.Commit()
+
+	return nil
+}
+
+// List returns every schedule ordered by op and currency.
+func List(ctx context.Context, dbConn *db.DB) ([]Schedule, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	it, err := txn.Get("fee", "id")
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.Get")
+	}
+
+	schedules := []Schedule{}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		s, ok := obj.(Schedule)
+		if !ok {
+			return nil, errors.New("couldn't type assert fee schedule")
+		}
+		schedules = append(schedules, s)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].Op != schedules[j].Op {
+			return schedules[i].Op < schedules[j].Op
+		}
+		return schedules[i].Currency < schedules[j].Currency
+	})
+
+	return schedules, nil
+}
+
+// Calculate returns the fee for moving amount with the op transaction type in
+// currency, zero if there's no schedule for it.
+func Calculate(txn *memdb.Txn, op, currency string, amount int64) (int64, error) {
+	for _, c := range []string{currency, AnyCurrency} {
+		raw, err := txn.First("fee", "id", id(op, c))
+		if err != nil {
+			return 0, errors.Wrap(err, "txn.First")
+		}
+		if raw == nil {
+			continue
+		}
+
+		s, ok := raw.(Schedule)
+		if !ok {
+			return 0, errors.New("couldn't type assert fee schedule")
+		}
+
+		return s.fee(amount), nil
+	}
+
+	return 0, nil
+}
+
+func id(op, currency string) string {
+	return op + "/" + currency
+}
+
+func (s Schedule) fee(amount int64) int64 {
+	fixed, rate := s.Fixed, s.Rate
+	for _, t := range s.Tiers {
+		if t.UpTo == 0 || amount <= t.UpTo {
+			fixed, rate = t.Fixed, t.Rate
+			break
+		}
+	}
+
+	f := fixed + amount*rate/10000
+	if f < s.Min {
+		f = s.Min
+	}
+	if s.Max > 0 && f > s.Max {
+		f = s.Max
+	}
+
+	return f
+}
+
+func (s Schedule) validate() error {
+	switch s.Op {
+	case ledger.TypeWithdraw, ledger.TypeTransfer:
+	default:
+		return errors.Wrapf(ErrInvalidSchedule, "fees can't be charged on %q", s.Op)
+	}
+
+	if s.Fixed < 0 || s.Rate < 0 || s.Min < 0 || s.Max < 0 {
+		return errors.Wrap(ErrInvalidSchedule, "negative fee")
+	}
+	if s.Max > 0 && s.Min > s.Max {
+		return errors.Wrap(ErrInvalidSchedule, "min is above max")
+	}
+
+	for i, t := range s.Tiers {
+		if t.Fixed < 0 || t.Rate < 0 {
+			return errors.Wrap(ErrInvalidSchedule, "negative fee")
+		}
+		if i > 0 && (s.Tiers[i-1].UpTo == 0 || (t.UpTo != 0 && t.UpTo <= s.Tiers[i-1].UpTo)) {
+			return errors.Wrap(ErrInvalidSchedule, "tiers must be in increasing order")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package fee_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/fee"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var ctx context.Context
+
+func TestFee(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	t.Run("feeInvalidSchedule", feeInvalidSchedule)
+	t.Run("feeWithdrawCaps", feeWithdrawCaps)
+	t.Run("feeTransferTiers", feeTransferTiers)
+}
+
+func feeInvalidSchedule(t *testing.T) {
+	err := fee.Set(ctx, test.MasterDB, fee.Schedule{Op: ledger.TypeDeposit, Fixed: 1})
+	assert.Equal(t, fee.ErrInvalidSchedule, errors.Cause(err))
+
+	err = fee.Set(ctx, test.MasterDB, fee.Schedule{Op: ledger.TypeWithdraw, Min: 10, Max: 5})
+	assert.Equal(t, fee.ErrInvalidSchedule, errors.Cause(err))
+}
+
+func feeWithdrawCaps(t *testing.T) {
+	// 2% with at least 5 and at most 50, GBP accounts pay a flat 3
+	err := fee.Set(ctx, test.MasterDB, fee.Schedule{Op: ledger.TypeWithdraw, Rate: 200, Min: 5, Max: 50})
+	assert.NoError(t, err)
+	err = fee.Set(ctx, test.MasterDB, fee.Schedule{Op: ledger.TypeWithdraw, Currency: "GBP", Fixed: 3})
+	assert.NoError(t, err)
+
+	userID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, 5000)
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		amount, fee int64
+	}{
+		{100, 5},
+		{1000, 20},
+		{4000, 50},
+	} {
+		q, err := user.QuoteFeeByID(ctx, test.MasterDB, userID, ledger.TypeWithdraw, tc.amount)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.fee, q.Fee)
+	}
+
+	tx, err := user.WithdrawByID(ctx, test.MasterDB, userID, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), tx.Fee)
+
+	balance, err := user.GetBalanceByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3980), balance)
+
+	// the fee has to be covered too
+	_, err = user.WithdrawByID(ctx, test.MasterDB, userID, 3950)
+	assert.Equal(t, user.ErrInsufficientFunds, err)
+
+	gbpID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Sam", Currency: "GBP"})
+	assert.NoError(t, err)
+
+	q, err := user.QuoteFeeByID(ctx, test.MasterDB, gbpID, ledger.TypeWithdraw, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), q.Fee)
+	assert.Equal(t, int64(1003), q.Total)
+}
+
+func feeTransferTiers(t *testing.T) {
+	err := fee.Set(ctx, test.MasterDB, fee.Schedule{
+		Op: ledger.TypeTransfer,
+		Tiers: []fee.Tier{
+			{UpTo: 1000, Fixed: 10},
+			{Rate: 100},
+		},
+	})
+	assert.NoError(t, err)
+
+	fromID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	toID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Kim"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, fromID, 5000)
+	assert.NoError(t, err)
+
+	tx, err := user.TransferByID(ctx, test.MasterDB, fromID, toID, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), tx.Fee)
+
+	tx, err = user.TransferByID(ctx, test.MasterDB, fromID, toID, 2000)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), tx.Fee)
+
+	balance, err := user.GetBalanceByID(ctx, test.MasterDB, fromID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2470), balance)
+
+	balance, err = user.GetBalanceByID(ctx, test.MasterDB, toID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2500), balance)
+
+	gbpID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Sam", Currency: "GBP"})
+	assert.NoError(t, err)
+	_, err = user.TransferByID(ctx, test.MasterDB, fromID, gbpID, 100)
+	assert.Equal(t, user.ErrCurrencyMismatch, err)
+}
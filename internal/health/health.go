@@ -0,0 +1,153 @@
+// Package health tells orchestrators whether the service is alive and
+// whether it should get traffic.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+)
+
+// Statuses of the service and of each check.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Checks readiness is made of.
+const (
+	CheckStorage  = "storage"
+	CheckStartup  = "startup"
+	CheckShutdown = "shutdown"
+)
+
+// Report is the outcome of the checks, with the reason of the failing ones.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// job is a background job and when it last reported it's running.
+type job struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// Checker keeps track of what the service needs before it can take traffic:
+// the storage, the startup recovery and the background jobs. It's ready once
+// started until it starts draining.
+type Checker struct {
+	dbConn *db.DB
+
+	mu       sync.Mutex
+	started  bool
+	draining bool
+	jobs     map[string]*job
+}
+
+// New creates a Checker for the storage of dbConn. It isn't ready before
+// Started is called.
+func New(dbConn *db.DB) *Checker {
+	return &Checker{
+		dbConn: dbConn,
+		jobs:   map[string]*job{},
+	}
+}
+
+// Started marks the startup, where the state is loaded and recovered, as
+// finished.
+func (c *Checker) Started() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.started = true
+}
+
+// Drain makes the service unready for good, so load balancers stop routing
+// to it before it shuts down.
+func (c *Checker) Drain() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.draining = true
+}
+
+// Beat reports that the job running every interval is alive. Jobs call it
+// when they start and after each run, a job that misses two beats makes the
+// service unready.
+func (c *Checker) Beat(name string, interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.jobs[name] = &job{interval: interval, last: time.Now()}
+}
+
+// Live reports whether the process is able to serve requests at all.
+func (c *Checker) Live() Report {
+	return Report{
+		Status: StatusOK,
+		Checks: map[string]string{},
+	}
+}
+
+// Ready reports whether the service should get traffic as of now.
+func (c *Checker) Ready(now time.Time) Report {
+	checks := map[string]string{
+		CheckStorage: StatusOK,
+	}
+	if err := c.checkStorage(); err != nil {
+		checks[CheckStorage] = err.Error()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	checks[CheckStartup] = StatusOK
+	if !c.started {
+		checks[CheckStartup] = "not finished"
+	}
+
+	checks[CheckShutdown] = StatusOK
+	if c.draining {
+		checks[CheckShutdown] = "draining"
+	}
+
+	for name, j := range c.jobs {
+		checks["job:"+name] = StatusOK
+		if since := now.Sub(j.last); since > 2*j.interval {
+			checks["job:"+name] = fmt.Sprintf("last ran %s ago", since.Round(time.Second))
+		}
+	}
+
+	r := Report{
+		Status: StatusOK,
+		Checks: checks,
+	}
+	for _, v := range checks {
+		if v != StatusOK {
+			r.Status = StatusUnavailable
+		}
+	}
+
+	return r
+}
+
+// checkStorage makes sure the storage can be read.
+func (c *Checker) checkStorage() error {
+	if c.dbConn == nil {
+		return errors.New("not loaded")
+	}
+
+	txn := c.dbConn.Txn(context.Background(), false)
+	defer txn.Abort()
+
+	if _, err := txn.First("user", "id"); err != nil {
+		return errors.Wrap(err, "txn.First")
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package health_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/health"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+func TestHealth(t *testing.T) {
+	defer tests.Recover(t)
+
+	t.Run("healthStartup", healthStartup)
+	t.Run("healthStalledJob", healthStalledJob)
+	t.Run("healthDrain", healthDrain)
+	t.Run("healthNoStorage", healthNoStorage)
+}
+
+func healthStartup(t *testing.T) {
+	c := health.New(test.MasterDB)
+	assert.Equal(t, health.StatusOK, c.Live().Status, "alive while starting")
+
+	r := c.Ready(time.Now())
+	assert.Equal(t, health.StatusUnavailable, r.Status)
+	assert.Equal(t, "not finished", r.Checks[health.CheckStartup])
+
+	c.Started()
+	r = c.Ready(time.Now())
+	assert.Equal(t, health.StatusOK, r.Status, "checks: %v", r.Checks)
+}
+
+func healthStalledJob(t *testing.T) {
+	c := health.New(test.MasterDB)
+	c.Started()
+	c.Beat("holds", time.Minute)
+
+	assert.Equal(t, health.StatusOK, c.Ready(time.Now().Add(time.Minute)).Status)
+
+	r := c.Ready(time.Now().Add(3 * time.Minute))
+	assert.Equal(t, health.StatusUnavailable, r.Status)
+	assert.Equal(t, "last ran 3m0s ago", r.Checks["job:holds"])
+}
+
+func healthDrain(t *testing.T) {
+	c := health.New(test.MasterDB)
+	c.Started()
+	c.Drain()
+
+	r := c.Ready(time.Now())
+	assert.Equal(t, health.StatusUnavailable, r.Status)
+	assert.Equal(t, "draining", r.Checks[health.CheckShutdown])
+	assert.Equal(t, health.StatusOK, c.Live().Status, "still alive while draining")
+}
+
+func healthNoStorage(t *testing.T) {
+	c := health.New(nil)
+	c.Started()
+
+	r := c.Ready(time.Now())
+	assert.Equal(t, health.StatusUnavailable, r.Status)
+	assert.Equal(t, "not loaded", r.Checks[health.CheckStorage])
+}
@@ -0,0 +1,400 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Formats an import can be read from.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Job statuses.
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// ChunkSize is how many rows are committed together.
+const ChunkSize = 500
+
+// columns are the fields of a row, CSV files name them in their header.
+var columns = []string{"name", "email", "country", "currency", "balance"}
+
+var (
+	ErrInvalidFormat = errors.New("format must be csv or ndjson")
+	ErrInvalidHeader = errors.New("csv header must name the columns: " + strings.Join(columns, ", "))
+	ErrNotFound      = errors.New("import job not found")
+)
+
+// Row is a user to import with their opening balance. Line is where the
+// row is in the file, starting at 1.
+type Row struct {
+	Line     int    `json:"-"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Country  string `json:"country"`
+	Currency string `json:"currency"`
+	Balance  int64  `json:"balance"`
+}
+
+// RowError is why the row on Line can't be imported.
+type RowError struct {
+	Line  int    `json:"line"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// Job is an import running in the background. Processed counts the rows
+// checked so far out of Total, Imported the ones that were, or would be for
+// a dry run, committed. Error is set when the job failed as a whole.
+type Job struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	DryRun     bool       `json:"dry_run"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Imported   int        `json:"imported"`
+	Failed     int        `json:"failed"`
+	Errors     []RowError `json:"errors"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Parse reads the rows of a CSV or NDJSON file and validates each of them.
+// It returns the valid rows and the errors of the others, and only fails
+// when the file can't be read at all.
+func Parse(r io.Reader, format string) ([]Row, []RowError, error) {
+	var (
+		rows []Row
+		errs []RowError
+		err  error
+	)
+
+	switch format {
+	case FormatCSV:
+		rows, errs, err = parseCSV(r)
+	case FormatNDJSON:
+		rows, errs, err = parseNDJSON(r)
+	default:
+		return nil, nil, ErrInvalidFormat
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Emails have to be unique in the file as well as in the wallet.
+	valid := rows[:0]
+	seen := map[string]int{}
+	for _, row := range rows {
+		rerrs := validate(row)
+		email := strings.ToLower(row.Email)
+		if line, ok := seen[email]; ok && email != "" {
+			rerrs = append(rerrs, RowError{Line: row.Line, Field: "email", Error: fmt.Sprintf("is already used on line %d", line)})
+		}
+		if len(rerrs) > 0 {
+			errs = append(errs, rerrs...)
+			continue
+		}
+
+		seen[email] = row.Line
+		valid = append(valid, row)
+	}
+
+	sortErrors(errs)
+
+	return valid, errs, nil
+}
+
+func parseCSV(r io.Reader) ([]Row, []RowError, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil, ErrInvalidHeader
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "csv.Read")
+	}
+
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for h := range index {
+		if !contains(columns, h) {
+			return nil, nil, ErrInvalidHeader
+		}
+	}
+	if _, ok := index["name"]; !ok {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var (
+		rows []Row
+		errs []RowError
+	)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			perr, ok := err.(*csv.ParseError)
+			if !ok {
+				return nil, nil, errors.Wrap(err, "csv.Read")
+			}
+			errs = append(errs, RowError{Line: perr.StartLine, Error: perr.Err.Error()})
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		if len(record) != len(header) {
+			errs = append(errs, RowError{Line: line, Error: fmt.Sprintf("has %d fields, the header has %d", len(record), len(header))})
+			continue
+		}
+
+		row := Row{
+			Line:     line,
+			Name:     field(record, "name"),
+			Email:    field(record, "email"),
+			Country:  field(record, "country"),
+			Currency: field(record, "currency"),
+		}
+		if b := field(record, "balance"); b != "" {
+			row.Balance, err = strconv.ParseInt(b, 10, 64)
+			if err != nil {
+				errs = append(errs, RowError{Line: line, Field: "balance", Error: "must be an integer"})
+				continue
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, errs, nil
+}
+
+func parseNDJSON(r io.Reader) ([]Row, []RowError, error) {
+	var (
+		rows []Row
+		errs []RowError
+	)
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for line := 1; s.Scan(); line++ {
+		b := bytes.TrimSpace(s.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		var row Row
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.DisallowUnknownFields()
+		if err := d.Decode(&row); err != nil {
+			errs = append(errs, RowError{Line: line, Error: "must be a JSON object with the fields: " + strings.Join(columns, ", ")})
+			continue
+		}
+		row.Line = line
+
+		rows = append(rows, row)
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "bufio.Scan")
+	}
+
+	return rows, errs, nil
+}
+
+func validate(row Row) []RowError {
+	var errs []RowError
+
+	u := user.User{
+		Name:     row.Name,
+		Email:    row.Email,
+		Country:  row.Country,
+		Currency: row.Currency,
+	}
+	if verrs, ok := u.Validate().(validation.Errors); ok {
+		for fld, err := range verrs {
+			errs = append(errs, RowError{Line: row.Line, Field: fld, Error: err.Error()})
+		}
+	}
+
+	if row.Balance < 0 {
+		errs = append(errs, RowError{Line: row.Line, Field: "balance", Error: "must be no less than 0"})
+	}
+
+	return errs
+}
+
+// Start creates the job importing rows, the valid rows returned by Parse,
+// and runs it in the background. errs are the errors Parse found in the
+// other rows, they count as processed and failed from the start.
+func Start(ctx context.Context, dbConn *db.DB, rows []Row, errs []RowError, dryRun bool) (*Job, error) {
+	failed := map[int]bool{}
+	for _, e := range errs {
+		failed[e.Line] = true
+	}
+
+	j := Job{
+		ID:        uuid.New().String(),
+		Status:    StatusRunning,
+		DryRun:    dryRun,
+		Total:     len(rows) + len(failed),
+		Processed: len(failed),
+		Failed:    len(failed),
+		Errors:    append([]RowError{}, errs...),
+		CreatedAt: time.Now(),
+	}
+
+	if err := put(ctx, dbConn, j); err != nil {
+		return nil, err
+	}
+
+	go run(context.Background(), dbConn, j, rows)
+
+	return &j, nil
+}
+
+// run imports the rows in chunks of ChunkSize and reports the progress on j
+// after each of them.
+func run(ctx context.Context, dbConn *db.DB, j Job, rows []Row) {
+	for start := 0; start < len(rows); start += ChunkSize {
+		end := start + ChunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		chunk := rows[start:end]
+
+		openings := make([]user.Opening, len(chunk))
+		for i, row := range chunk {
+			openings[i] = user.Opening{
+				User: user.User{
+					Name:     row.Name,
+					Email:    row.Email,
+					Country:  row.Country,
+					Currency: row.Currency,
+				},
+				Balance: row.Balance,
+			}
+		}
+
+		errs, err := user.ImportBatch(ctx, dbConn, openings, j.DryRun)
+		if err != nil {
+			slog.Error("couldn't run import job", "job_id", j.ID, "error", err)
+			j.Status = StatusFailed
+			j.Error = err.Error()
+			break
+		}
+
+		// Copy the errors so the job stored before isn't changed.
+		jerrs := append([]RowError{}, j.Errors...)
+		for i, err := range errs {
+			if err != nil {
+				jerrs = append(jerrs, RowError{Line: chunk[i].Line, Field: "email", Error: err.Error()})
+				j.Failed++
+				continue
+			}
+			j.Imported++
+		}
+		sortErrors(jerrs)
+		j.Errors = jerrs
+		j.Processed += len(chunk)
+
+		if err := put(ctx, dbConn, j); err != nil {
+			slog.Error("couldn't run import job", "job_id", j.ID, "error", err)
+		}
+	}
+
+	if j.Status == StatusRunning {
+		j.Status = StatusDone
+	}
+	now := time.Now()
+	j.FinishedAt = &now
+
+	if err := put(ctx, dbConn, j); err != nil {
+		slog.Error("couldn't run import job", "job_id", j.ID, "error", err)
+	}
+}
+
+// GetJob returns the job, ErrNotFound if there's none.
+func GetJob(ctx context.Context, dbConn *db.DB, id string) (*Job, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	raw, err := txn.First("import_job", "id", id)
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.First")
+	}
+	if raw == nil {
+		return nil, ErrNotFound
+	}
+
+	j, ok := raw.(Job)
+	if !ok {
+		return nil, errors.New("couldn't type assert import job")
+	}
+
+	return &j, nil
+}
+
+func put(ctx context.Context, dbConn *db.DB, j Job) error {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	if err := txn.Insert("import_job", j); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	txn.Commit()
+
+	return nil
+}
+
+func sortErrors(errs []RowError) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Field < errs[j].Field
+	})
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
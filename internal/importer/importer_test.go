@@ -0,0 +1,145 @@
+package importer_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/importer"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var ctx context.Context
+
+func TestImporter(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	t.Run("importerParseCSV", importerParseCSV)
+	t.Run("importerParseNDJSON", importerParseNDJSON)
+	t.Run("importerInvalidHeader", importerInvalidHeader)
+	t.Run("importerDryRun", importerDryRun)
+	t.Run("importerRun", importerRun)
+}
+
+const usersCSV = `name,email,currency,balance
+Alex,alex@import.test,EUR,1500
+,nameless@import.test,EUR,10
+Kim,kim@import.test,XXX1,10
+Sam,ALEX@import.test,EUR,10
+Jo,jo@import.test,EUR,ten
+Lee,,GBP,0
+`
+
+func importerParseCSV(t *testing.T) {
+	rows, errs, err := importer.Parse(strings.NewReader(usersCSV), importer.FormatCSV)
+	assert.NoError(t, err)
+
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, importer.Row{Line: 2, Name: "Alex", Email: "alex@import.test", Currency: "EUR", Balance: 1500}, rows[0])
+		assert.Equal(t, 7, rows[1].Line)
+	}
+	assert.Equal(t, []importer.RowError{
+		{Line: 3, Field: "name", Error: "cannot be blank"},
+		{Line: 4, Field: "currency", Error: "must be a valid currency code"},
+		{Line: 5, Field: "email", Error: "is already used on line 2"},
+		{Line: 6, Field: "balance", Error: "must be an integer"},
+	}, errs)
+}
+
+func importerParseNDJSON(t *testing.T) {
+	in := `{"name":"Alex","balance":100}
+
+{"name":"Kim","balance":-1}
+{"name":"Sam","bonus":5}
+`
+	rows, errs, err := importer.Parse(strings.NewReader(in), importer.FormatNDJSON)
+	assert.NoError(t, err)
+
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, int64(100), rows[0].Balance)
+	}
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, importer.RowError{Line: 3, Field: "balance", Error: "must be no less than 0"}, errs[0])
+		assert.Equal(t, 4, errs[1].Line)
+	}
+}
+
+func importerInvalidHeader(t *testing.T) {
+	_, _, err := importer.Parse(strings.NewReader("name,password\nAlex,x\n"), importer.FormatCSV)
+	assert.Equal(t, importer.ErrInvalidHeader, errors.Cause(err))
+
+	_, _, err = importer.Parse(strings.NewReader(""), "xml")
+	assert.Equal(t, importer.ErrInvalidFormat, errors.Cause(err))
+}
+
+// wait returns the job once it's no longer running.
+func wait(t *testing.T, id string) *importer.Job {
+	for i := 0; i < 100; i++ {
+		j, err := importer.GetJob(ctx, test.MasterDB, id)
+		assert.NoError(t, err)
+		if j.Status != importer.StatusRunning {
+			return j
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("import job didn't finish")
+	return nil
+}
+
+func importerDryRun(t *testing.T) {
+	rows, errs, err := importer.Parse(strings.NewReader(usersCSV), importer.FormatCSV)
+	assert.NoError(t, err)
+
+	j, err := importer.Start(ctx, test.MasterDB, rows, errs, true)
+	assert.NoError(t, err)
+
+	j = wait(t, j.ID)
+	assert.Equal(t, importer.StatusDone, j.Status)
+	assert.Equal(t, 6, j.Total)
+	assert.Equal(t, 6, j.Processed)
+	assert.Equal(t, 2, j.Imported)
+	assert.Equal(t, 4, j.Failed)
+
+	_, err = user.GetByEmail(ctx, test.MasterDB, "alex@import.test")
+	assert.Equal(t, user.ErrNotFound, errors.Cause(err), "dry run shouldn't commit")
+}
+
+func importerRun(t *testing.T) {
+	rows, errs, err := importer.Parse(strings.NewReader(usersCSV), importer.FormatCSV)
+	assert.NoError(t, err)
+
+	j, err := importer.Start(ctx, test.MasterDB, rows, errs, false)
+	assert.NoError(t, err)
+	j = wait(t, j.ID)
+	assert.Equal(t, 2, j.Imported)
+
+	u, err := user.GetByEmail(ctx, test.MasterDB, "alex@import.test")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), u.Balance)
+
+	// importing again fails on the taken email
+	j, err = importer.Start(ctx, test.MasterDB, rows, nil, false)
+	assert.NoError(t, err)
+	j = wait(t, j.ID)
+	assert.Equal(t, 1, j.Imported)
+	assert.Equal(t, []importer.RowError{{Line: 2, Field: "email", Error: user.ErrEmailTaken.Error()}}, j.Errors)
+}
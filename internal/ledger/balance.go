@@ -0,0 +1,199 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+)
+
+// BalanceCheckpointEvery is how many postings an account gets between two
+// balance checkpoints. Historical balances start from the closest
+// checkpoint, so they never sum up more postings than this.
+const BalanceCheckpointEvery = 100
+
+// Entry is a posting as stored for balance history, with the account's
+// balance right after it.
+type Entry struct {
+	AccountID     string    `json:"-"`
+	Seq           uint64    `json:"seq"`
+	TransactionID string    `json:"transaction_id"`
+	Type          string    `json:"type"`
+	Amount        int64     `json:"amount"`
+	Balance       int64     `json:"balance"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BalanceCheckpoint is the balance of an account after the transaction with
+// Seq.
+type BalanceCheckpoint struct {
+	AccountID string
+	Seq       uint64
+	Count     uint64
+	Balance   int64
+	CreatedAt time.Time
+}
+
+// account is the running state of an account's history.
+type account struct {
+	ID      string
+	Count   uint64
+	Balance int64
+}
+
+// Range is the history of an account over [From, To).
+type Range struct {
+	AccountID string    `json:"account_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Opening   int64     `json:"opening"`
+	Closing   int64     `json:"closing"`
+	Movements []Entry   `json:"movements"`
+}
+
+// BalanceAt returns the balance of the account as it was at t, that is with
+// every transaction created up to and including t. Transactions are assumed
+// to be recorded in the order they were created.
+func BalanceAt(txn *memdb.Txn, accountID string, at time.Time) (int64, error) {
+	balance, _, err := balanceBefore(txn, accountID, at.Add(time.Nanosecond))
+	if err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+// RangeOf returns the balances of the account at the start and the end of
+// [from, to) and every posting in between.
+func RangeOf(txn *memdb.Txn, accountID string, from, to time.Time) (Range, error) {
+	r := Range{
+		AccountID: accountID,
+		From:      from,
+		To:        to,
+		Movements: []Entry{},
+	}
+
+	opening, seq, err := balanceBefore(txn, accountID, from)
+	if err != nil {
+		return r, err
+	}
+	r.Opening = opening
+	r.Closing = opening
+
+	it, err := txn.LowerBound("entry", "id", accountID, seq+1)
+	if err != nil {
+		return r, errors.Wrap(err, "txn.LowerBound")
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		e, ok := obj.(Entry)
+		if !ok {
+			return r, errors.New("couldn't type assert entry")
+		}
+		if e.AccountID != accountID || !e.CreatedAt.Before(to) {
+			break
+		}
+		r.Movements = append(r.Movements, e)
+		r.Closing = e.Balance
+	}
+
+	return r, nil
+}
+
+// balanceBefore returns the balance of the account with the transactions
+// created before t and the seq of the last of them.
+func balanceBefore(txn *memdb.Txn, accountID string, t time.Time) (int64, uint64, error) {
+	// Start from the last checkpoint before t.
+	var cp BalanceCheckpoint
+
+	it, err := txn.LowerBound("balance_checkpoint", "id", accountID, uint64(0))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "txn.LowerBound")
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		c, ok := obj.(BalanceCheckpoint)
+		if !ok {
+			return 0, 0, errors.New("couldn't type assert balance checkpoint")
+		}
+		if c.AccountID != accountID || !c.CreatedAt.Before(t) {
+			break
+		}
+		cp = c
+	}
+
+	// Then add up the postings after it.
+	balance, seq := cp.Balance, cp.Seq
+
+	it, err = txn.LowerBound("entry", "id", accountID, cp.Seq+1)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "txn.LowerBound")
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		e, ok := obj.(Entry)
+		if !ok {
+			return 0, 0, errors.New("couldn't type assert entry")
+		}
+		if e.AccountID != accountID || !e.CreatedAt.Before(t) {
+			break
+		}
+		balance, seq = e.Balance, e.Seq
+	}
+
+	return balance, seq, nil
+}
+
+// recordEntries stores the postings of t for balance history and
+// checkpoints the accounts that reached BalanceCheckpointEvery postings.
+func recordEntries(txn *memdb.Txn, t Transaction) error {
+	amounts := map[string]int64{}
+	for _, p := range t.Postings {
+		amounts[p.AccountID] += p.Amount
+	}
+
+	for _, id := range t.Accounts {
+		raw, err := txn.First("account", "id", id)
+		if err != nil {
+			return errors.Wrap(err, "txn.First")
+		}
+		a, ok := raw.(account)
+		if !ok {
+			a = account{ID: id}
+		}
+
+		a.Count++
+		a.Balance += amounts[id]
+
+		e := Entry{
+			AccountID:     id,
+			Seq:           t.Seq,
+			TransactionID: t.ID,
+			Type:          t.Type,
+			Amount:        amounts[id],
+			Balance:       a.Balance,
+			CreatedAt:     t.CreatedAt,
+		}
+
+		if err := txn.Insert("account", a); err != nil {
+			return errors.Wrap(err, "txn.Insert")
+		}
+		if err := txn.Insert("entry", e); err != nil {
+			return errors.Wrap(err, "txn.Insert")
+		}
+
+		if a.Count%BalanceCheckpointEvery != 0 {
+			continue
+		}
+
+		c := BalanceCheckpoint{
+			AccountID: id,
+			Seq:       t.Seq,
+			Count:     a.Count,
+			Balance:   a.Balance,
+			CreatedAt: t.CreatedAt,
+		}
+		if err := txn.Insert("balance_checkpoint", c); err != nil {
+			return errors.Wrap(err, "txn.Insert")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package ledger
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+)
+
+// Genesis is the PrevHash of the first transaction.
+var Genesis = strings.Repeat("0", sha256.Size*2)
+
+var (
+	ErrInvalidSigningKey = errors.New("invalid signing key")
+)
+
+// Checkpoint is a signature over the hash of the transaction with Seq. As
+// every hash covers all transactions before it, a valid checkpoint vouches
+// for the whole ledger up to Seq. PublicKey is the base64 key that verifies
+// Signature.
+type Checkpoint struct {
+	Seq       uint64    `json:"seq"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	PublicKey string    `json:"public_key"`
+	Signature string    `json:"signature"`
+}
+
+// Snapshot is every transaction and checkpoint of the ledger, in order.
+type Snapshot struct {
+	Transactions []Transaction `json:"transactions"`
+	Checkpoints  []Checkpoint  `json:"checkpoints"`
+}
+
+// Break is the first link of the chain that doesn't verify.
+type Break struct {
+	Seq           uint64 `json:"seq"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	Reason        string `json:"reason"`
+}
+
+// Hash returns the hex SHA-256 of the transaction's content and PrevHash.
+// Changing any recorded field of a transaction changes its hash and so
+// breaks the link to every transaction after it.
+func Hash(t Transaction) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%d\n%s\n%s\n%s\n%d\n%d\n", t.PrevHash, t.Seq, t.ID, t.Type, t.UserID, t.Amount, t.Fee)
+	for _, p := range t.Postings {
+		fmt.Fprintf(h, "%s:%d\n", p.AccountID, p.Amount)
+	}
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", t.Risk, t.RiskRuleID, t.ReversalOf, t.CreatedAt.UTC().Format(time.RFC3339Nano))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ParseSigningKey reads an ed25519 private key from its base64 seed.
+func ParseSigningKey(s string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrInvalidSigningKey
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// GenerateSigningKey returns a new random ed25519 private key.
+func GenerateSigningKey(rand io.Reader) (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return key, nil
+}
+
+// CreateCheckpoint signs the latest transaction with key unless it's already
+// signed. It returns nil when there's nothing new to sign.
+func CreateCheckpoint(ctx context.Context, dbConn *db.DB, key ed25519.PrivateKey, now time.Time) (*Checkpoint, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	raw, err := txn.Last("transaction", "seq")
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.Last")
+	}
+	t, ok := raw.(Transaction)
+	if !ok {
+		return nil, nil
+	}
+
+	last, err := lastCheckpoint(txn)
+	if err != nil {
+		return nil, err
+	}
+	if last.Seq >= t.Seq {
+		return nil, nil
+	}
+
+	c := Checkpoint{
+		Seq:       t.Seq,
+		Hash:      t.Hash,
+		CreatedAt: now,
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+	}
+	c.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, c.message()))
+
+	if err := txn.Insert("checkpoint", c); err != nil {
+		return nil, errors.Wrap(err, "txn.Insert")
+	}
+
+	txn.Commit()
+
+	return &c, nil
+}
+
+// TakeSnapshot returns the whole ledger as of now.
+func TakeSnapshot(ctx context.Context, dbConn *db.DB) (*Snapshot, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	s := Snapshot{
+		Transactions: []Transaction{},
+		Checkpoints:  []Checkpoint{},
+	}
+
+	it, err := txn.Get("transaction", "seq")
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.Get")
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		t, ok := obj.(Transaction)
+		if !ok {
+			return nil, errors.New("couldn't type assert transaction")
+		}
+		s.Transactions = append(s.Transactions, t)
+	}
+
+	it, err = txn.Get("checkpoint", "seq")
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.Get")
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		c, ok := obj.(Checkpoint)
+		if !ok {
+			return nil, errors.New("couldn't type assert checkpoint")
+		}
+		s.Checkpoints = append(s.Checkpoints, c)
+	}
+
+	return &s, nil
+}
+
+// Verify walks the snapshot and returns the first link that doesn't hold,
+// nil if the whole chain verifies. Checkpoints have to be signed by pub, or
+// by the key they carry when pub is nil, which only proves the snapshot is
+// consistent with itself.
+func Verify(s Snapshot, pub ed25519.PublicKey) *Break {
+	hashes := map[uint64]string{}
+
+	prev := Genesis
+	for i, t := range s.Transactions {
+		seq := uint64(i + 1)
+		switch {
+		case t.Seq != seq:
+			return &Break{Seq: seq, TransactionID: t.ID, Reason: fmt.Sprintf("expected seq %d, got %d", seq, t.Seq)}
+		case t.PrevHash != prev:
+			return &Break{Seq: seq, TransactionID: t.ID, Reason: "previous hash doesn't match"}
+		case Hash(t) != t.Hash:
+			return &Break{Seq: seq, TransactionID: t.ID, Reason: "hash doesn't match content"}
+		}
+
+		var sum int64
+		for _, p := range t.Postings {
+			sum += p.Amount
+		}
+		if sum != 0 {
+			return &Break{Seq: seq, TransactionID: t.ID, Reason: "postings don't balance"}
+		}
+
+		hashes[t.Seq] = t.Hash
+		prev = t.Hash
+	}
+
+	for _, c := range s.Checkpoints {
+		key := pub
+		if key == nil {
+			b, err := base64.StdEncoding.DecodeString(c.PublicKey)
+			if err != nil || len(b) != ed25519.PublicKeySize {
+				return &Break{Seq: c.Seq, Reason: "checkpoint has an invalid public key"}
+			}
+			key = ed25519.PublicKey(b)
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(c.Signature)
+		if err != nil || !ed25519.Verify(key, c.message(), sig) {
+			return &Break{Seq: c.Seq, Reason: "checkpoint signature doesn't verify"}
+		}
+
+		h, ok := hashes[c.Seq]
+		if !ok {
+			return &Break{Seq: c.Seq, Reason: "checkpoint is past the end of the ledger"}
+		}
+		if h != c.Hash {
+			return &Break{Seq: c.Seq, Reason: "checkpoint doesn't match the ledger"}
+		}
+	}
+
+	return nil
+}
+
+// lastCheckpoint returns the latest checkpoint, the zero one if there's none.
+func lastCheckpoint(txn *memdb.Txn) (Checkpoint, error) {
+	raw, err := txn.Last("checkpoint", "seq")
+	if err != nil {
+		return Checkpoint{}, errors.Wrap(err, "txn.Last")
+	}
+
+	c, _ := raw.(Checkpoint)
+	return c, nil
+}
+
+func (c Checkpoint) message() []byte {
+	return []byte(fmt.Sprintf("%d\n%s\n%s", c.Seq, c.Hash, c.CreatedAt.UTC().Format(time.RFC3339Nano)))
+}
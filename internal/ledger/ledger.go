@@ -0,0 +1,209 @@
+package ledger
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+)
+
+// System accounts. External is the other side of money entering or leaving
+// the wallet, so every transaction can be recorded with balanced postings.
+// House is the operator's account that receives captured funds and pays out
+// winnings. Revenue collects the fees.
+const (
+	External = "external"
+	House    = "house"
+	Revenue  = "house:revenue"
+)
+
+// Transaction types.
+const (
+	TypeDeposit  = "deposit"
+	TypeWithdraw = "withdraw"
+	TypeTransfer = "transfer"
+	TypeCapture  = "capture"
+	TypeReversal = "reversal"
+	TypePayout   = "payout"
+	TypeDebit    = "debit"
+	TypeCredit   = "credit"
+	TypeImport   = "import"
+
+	TypeBonus        = "bonus"
+	TypeBonusBet     = "bonus_bet"
+	TypeBonusConvert = "bonus_convert"
+	TypeBonusExpire  = "bonus_expire"
+)
+
+// BonusAccount returns the account holding the user's bonus funds.
+func BonusAccount(userID string) string {
+	return userID + ":bonus"
+}
+
+var (
+	ErrUnbalanced = errors.New("postings don't balance")
+	ErrNotFound   = errors.New("transaction not found")
+)
+
+// Posting is a signed movement on a single account. Credits are positive,
+// debits are negative.
+type Posting struct {
+	AccountID string `json:"account_id"`
+	Amount    int64  `json:"amount"`
+}
+
+// Transaction is an entry in the ledger. The amounts of its postings always
+// sum up to zero. Fee is the part of the user's debit collected as a fee on
+// top of Amount. Risk and RiskRuleID record the risk rules decision the
+// transaction was committed with. ReversalOf links a reversal to the
+// transaction it compensates. Hash chains the transaction to the one
+// recorded before it, see Hash.
+type Transaction struct {
+	ID         string    `json:"id"`
+	Seq        uint64    `json:"seq"`
+	Type       string    `json:"type"`
+	UserID     string    `json:"user_id"`
+	Amount     int64     `json:"amount"`
+	Fee        int64     `json:"fee,omitempty"`
+	Postings   []Posting `json:"postings"`
+	Accounts   []string  `json:"-"`
+	Risk       string    `json:"risk,omitempty"`
+	RiskRuleID string    `json:"risk_rule_id,omitempty"`
+	ReversalOf string    `json:"reversal_of,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+// Record appends t to the ledger as part of txn. ID, Seq, Accounts and the
+// hashes are assigned here, CreatedAt only when it's not set. The postings
+// are also added to the balance history of their accounts, see BalanceAt.
+func Record(txn *memdb.Txn, t Transaction) (Transaction, error) {
+	var sum int64
+	seen := map[string]bool{}
+	t.Accounts = nil
+	for _, p := range t.Postings {
+		sum += p.Amount
+		if !seen[p.AccountID] {
+			seen[p.AccountID] = true
+			t.Accounts = append(t.Accounts, p.AccountID)
+		}
+	}
+	if sum != 0 {
+		return t, ErrUnbalanced
+	}
+
+	raw, err := txn.Last("transaction", "seq")
+	if err != nil {
+		return t, errors.Wrap(err, "txn.Last")
+	}
+	if last, ok := raw.(Transaction); ok {
+		t.Seq = last.Seq + 1
+		t.PrevHash = last.Hash
+	} else {
+		t.Seq = 1
+		t.PrevHash = Genesis
+	}
+
+	t.ID = uuid.New().String()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	t.Hash = Hash(t)
+
+	if err := txn.Insert("transaction", t); err != nil {
+		return t, errors.Wrap(err, "txn.Insert")
+	}
+	if err := recordEntries(txn, t); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// GetByID returns the transaction, ErrNotFound if there's none.
+func GetByID(txn *memdb.Txn, id string) (Transaction, error) {
+	raw, err := txn.First("transaction", "id", id)
+	if err != nil {
+		return Transaction{}, errors.Wrap(err, "txn.First")
+	}
+	if raw == nil {
+		return Transaction{}, ErrNotFound
+	}
+
+	t, ok := raw.(Transaction)
+	if !ok {
+		return Transaction{}, errors.New("couldn't type assert transaction")
+	}
+
+	return t, nil
+}
+
+// ReversalOf returns the transaction reversing id, ErrNotFound if there's none.
+func ReversalOf(txn *memdb.Txn, id string) (Transaction, error) {
+	raw, err := txn.First("transaction", "reversal_of", id)
+	if err != nil {
+		return Transaction{}, errors.Wrap(err, "txn.First")
+	}
+	if raw == nil {
+		return Transaction{}, ErrNotFound
+	}
+
+	t, ok := raw.(Transaction)
+	if !ok {
+		return Transaction{}, errors.New("couldn't type assert transaction")
+	}
+
+	return t, nil
+}
+
+// ListByUserSince returns the transactions of userID created at since or
+// later, oldest first.
+func ListByUserSince(txn *memdb.Txn, userID string, since time.Time) ([]Transaction, error) {
+	var ts []Transaction
+
+	it, err := txn.LowerBound("transaction", "user_created", userID, since)
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.LowerBound")
+	}
+
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		t, ok := obj.(Transaction)
+		if !ok {
+			return nil, errors.New("couldn't type assert transaction")
+		}
+		if t.UserID != userID {
+			break
+		}
+		ts = append(ts, t)
+	}
+
+	return ts, nil
+}
+
+// ListByAccount returns every transaction with a posting on accountID in the
+// order they were recorded.
+func ListByAccount(txn *memdb.Txn, accountID string) ([]Transaction, error) {
+	var ts []Transaction
+
+	it, err := txn.Get("transaction", "account", accountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.Get")
+	}
+
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		t, ok := obj.(Transaction)
+		if !ok {
+			return nil, errors.New("couldn't type assert transaction")
+		}
+		ts = append(ts, t)
+	}
+
+	sort.Slice(ts, func(i, j int) bool {
+		return ts[i].Seq < ts[j].Seq
+	})
+
+	return ts, nil
+}
@@ -0,0 +1,175 @@
+package ledger_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var (
+	ctx context.Context
+	key ed25519.PrivateKey
+)
+
+func TestChain(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	var err error
+	key, err = ledger.GenerateSigningKey(rand.Reader)
+	assert.NoError(t, err)
+
+	t.Run("chainVerifies", chainVerifies)
+	t.Run("chainEditedAmount", chainEditedAmount)
+	t.Run("chainForgedCheckpoint", chainForgedCheckpoint)
+}
+
+func TestBalanceHistory(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	t.Run("balanceAt", balanceAt)
+	t.Run("balanceRange", balanceRange)
+}
+
+const historyAccount = "history"
+
+var historyStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// recordHistory credits historyAccount with 1, 2, 3... a minute apart, going
+// past a few balance checkpoints.
+func recordHistory(t *testing.T) {
+	txn := test.MasterDB.Txn(tests.Context(), true)
+	defer txn.Abort()
+
+	for i := 0; i < 2*ledger.BalanceCheckpointEvery+50; i++ {
+		_, err := ledger.Record(txn, ledger.Transaction{
+			Type:   ledger.TypeDeposit,
+			Amount: int64(i + 1),
+			Postings: []ledger.Posting{
+				{AccountID: historyAccount, Amount: int64(i + 1)},
+				{AccountID: ledger.External, Amount: -int64(i + 1)},
+			},
+			CreatedAt: historyStart.Add(time.Duration(i) * time.Minute),
+		})
+		assert.NoError(t, err)
+	}
+
+	txn.Commit()
+}
+
+func balanceAt(t *testing.T) {
+	recordHistory(t)
+
+	txn := test.MasterDB.Txn(tests.Context(), false)
+	defer txn.Abort()
+
+	b, err := ledger.BalanceAt(txn, historyAccount, historyStart.Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), b, "nothing recorded yet")
+
+	for _, k := range []int{0, 99, 100, 150, 249, 500} {
+		b, err := ledger.BalanceAt(txn, historyAccount, historyStart.Add(time.Duration(k)*time.Minute))
+		assert.NoError(t, err)
+
+		n := int64(k + 1)
+		if k >= 250 {
+			n = 250
+		}
+		assert.Equal(t, n*(n+1)/2, b, "at minute %d", k)
+	}
+}
+
+func balanceRange(t *testing.T) {
+	txn := test.MasterDB.Txn(tests.Context(), false)
+	defer txn.Abort()
+
+	from := historyStart.Add(100 * time.Minute)
+	r, err := ledger.RangeOf(txn, historyAccount, from, from.Add(10*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5050), r.Opening)
+	assert.Equal(t, int64(6105), r.Closing)
+	assert.Len(t, r.Movements, 10)
+	assert.Equal(t, int64(101), r.Movements[0].Amount)
+	assert.Equal(t, r.Closing, r.Movements[9].Balance)
+}
+
+func snapshot(t *testing.T) ledger.Snapshot {
+	userID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, 500)
+	assert.NoError(t, err)
+
+	_, err = ledger.CreateCheckpoint(ctx, test.MasterDB, key, time.Now())
+	assert.NoError(t, err)
+
+	s, err := ledger.TakeSnapshot(ctx, test.MasterDB)
+	assert.NoError(t, err)
+	return *s
+}
+
+func chainVerifies(t *testing.T) {
+	s := snapshot(t)
+	assert.Equal(t, ledger.Genesis, s.Transactions[0].PrevHash)
+	assert.Nil(t, ledger.Verify(s, key.Public().(ed25519.PublicKey)))
+
+	// nothing new to sign
+	c, err := ledger.CreateCheckpoint(ctx, test.MasterDB, key, time.Now())
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+}
+
+func chainEditedAmount(t *testing.T) {
+	s := snapshot(t)
+
+	edited := s.Transactions[2]
+	edited.Amount = 1
+	edited.Postings = []ledger.Posting{
+		{AccountID: edited.Postings[0].AccountID, Amount: 1},
+		{AccountID: edited.Postings[1].AccountID, Amount: -1},
+	}
+	s.Transactions[2] = edited
+
+	b := ledger.Verify(s, nil)
+	assert.NotNil(t, b)
+	assert.Equal(t, uint64(3), b.Seq)
+	assert.Equal(t, s.Transactions[2].ID, b.TransactionID)
+
+	// rehashing the edited entry breaks the next link instead
+	s.Transactions[2].Hash = ledger.Hash(s.Transactions[2])
+	b = ledger.Verify(s, nil)
+	assert.NotNil(t, b)
+	assert.Equal(t, uint64(4), b.Seq)
+}
+
+func chainForgedCheckpoint(t *testing.T) {
+	s := snapshot(t)
+
+	other, err := ledger.GenerateSigningKey(rand.Reader)
+	assert.NoError(t, err)
+
+	b := ledger.Verify(s, other.Public().(ed25519.PublicKey))
+	assert.NotNil(t, b)
+	assert.Equal(t, "checkpoint signature doesn't verify", b.Reason)
+}
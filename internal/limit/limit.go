@@ -0,0 +1,260 @@
+package limit
+
+import (
+	"context"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+)
+
+// Global is the ID of the limits that apply to every user. Per-user limits
+// are stored under the user ID and override the global ones field by field.
+const Global = "global"
+
+// Unlimited set on a per-user limit lifts the global one.
+const Unlimited = -1
+
+var (
+	ErrLimitExceeded = errors.New("transaction limit exceeded")
+)
+
+// Window caps the totals a user can move within a rolling period. Only the
+// deposits, withdrawals and transfers users make themselves are counted, and
+// the totals leave out what was reversed of them. Zero means unlimited.
+type Window struct {
+	Deposit  int64 `json:"deposit,omitempty"`
+	Withdraw int64 `json:"withdraw,omitempty"`
+	Count    int64 `json:"count,omitempty"`
+}
+
+// Validate checks the window has no negative cap other than Unlimited.
+func (w Window) Validate() error {
+	return validation.ValidateStruct(&w, w.Rules()...)
+}
+
+// Rules are the rules of the caps of the window.
+func (w *Window) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&w.Deposit, validation.Min(Unlimited)),
+		validation.Field(&w.Withdraw, validation.Min(Unlimited)),
+		validation.Field(&w.Count, validation.Min(Unlimited)),
+	}
+}
+
+// Limits for a single user or for everyone when ID is Global. Zero means
+// unlimited, or the global limit for a user.
+type Limits struct {
+	ID        string `json:"id,omitempty"`
+	MinAmount int64  `json:"min_amount,omitempty"`
+	MaxAmount int64  `json:"max_amount,omitempty"`
+	Daily     Window `json:"daily"`
+	Weekly    Window `json:"weekly"`
+	Monthly   Window `json:"monthly"`
+}
+
+// Validate checks the limits have no negative value other than Unlimited.
+func (l Limits) Validate() error {
+	return validation.ValidateStruct(&l, l.Rules()...)
+}
+
+// Rules are the rules of the fields a client can set.
+func (l *Limits) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&l.MinAmount, validation.Min(Unlimited)),
+		validation.Field(&l.MaxAmount, validation.Min(Unlimited)),
+		validation.Field(&l.Daily),
+		validation.Field(&l.Weekly),
+		validation.Field(&l.Monthly),
+	}
+}
+
+// Set stores l under l.ID, replacing what's there.
+func Set(ctx context.Context, dbConn *db.DB, l Limits) error {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	if err := txn.Insert("limit", l); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	txn.Commit()
+
+	return nil
+}
+
+// Delete removes the limits stored under id, the overrides of a user, which
+// then gets the global limits again.
+func Delete(ctx context.Context, dbConn *db.DB, id string) error {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	if _, err := txn.DeleteAll("limit", "id", id); err != nil {
+		return errors.Wrap(err, "txn.DeleteAll")
+	}
+
+	txn.Commit()
+
+	return nil
+}
+
+// Get returns the limits in effect for userID: the global limits with the
+// user's overrides applied.
+func Get(ctx context.Context, dbConn *db.DB, userID string) (Limits, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	l, err := effective(txn, userID)
+	if err != nil {
+		return l, errors.Wrap(err, "")
+	}
+
+	return l, nil
+}
+
+// Check returns ErrLimitExceeded if the user can't move amount with the op
+// transaction type at now.
+func Check(txn *memdb.Txn, userID string, op string, amount int64, now time.Time) error {
+	l, err := effective(txn, userID)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if l.MinAmount > 0 && amount < l.MinAmount {
+		return errors.Wrapf(ErrLimitExceeded, "minimum amount is %d", l.MinAmount)
+	}
+	if l.MaxAmount > 0 && amount > l.MaxAmount {
+		return errors.Wrapf(ErrLimitExceeded, "maximum amount is %d", l.MaxAmount)
+	}
+
+	windows := []struct {
+		name   string
+		period time.Duration
+		w      Window
+	}{
+		{"daily", 24 * time.Hour, l.Daily},
+		{"weekly", 7 * 24 * time.Hour, l.Weekly},
+		{"monthly", 30 * 24 * time.Hour, l.Monthly},
+	}
+
+	// The longest window covers the others.
+	ts, err := ledger.ListByUserSince(txn, userID, now.Add(-windows[len(windows)-1].period))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	for _, win := range windows {
+		var total, count int64
+		since := now.Add(-win.period)
+		counted := map[string]ledger.Transaction{}
+		for _, t := range ts {
+			if t.CreatedAt.Before(since) {
+				continue
+			}
+
+			// Reversals take back from the total of what they reverse when
+			// that's in the window too.
+			if t.Type == ledger.TypeReversal {
+				if orig, ok := counted[t.ReversalOf]; ok && orig.Type == op {
+					total -= t.Amount
+				}
+				continue
+			}
+
+			if !userInitiated[t.Type] {
+				continue
+			}
+			counted[t.ID] = t
+			count++
+			if t.Type == op {
+				total += t.Amount
+			}
+		}
+
+		if win.w.Count > 0 && count+1 > win.w.Count {
+			return errors.Wrapf(ErrLimitExceeded, "%s limit of %d transactions", win.name, win.w.Count)
+		}
+
+		var max int64
+		switch op {
+		case ledger.TypeDeposit:
+			max = win.w.Deposit
+		case ledger.TypeWithdraw:
+			max = win.w.Withdraw
+		}
+		if max > 0 && total+amount > max {
+			return errors.Wrapf(ErrLimitExceeded, "%s %s limit of %d", win.name, op, max)
+		}
+	}
+
+	return nil
+}
+
+// userInitiated are the types of the transactions the windows count.
+var userInitiated = map[string]bool{
+	ledger.TypeDeposit:  true,
+	ledger.TypeWithdraw: true,
+	ledger.TypeTransfer: true,
+}
+
+func effective(txn *memdb.Txn, userID string) (Limits, error) {
+	global, err := get(txn, Global)
+	if err != nil {
+		return global, errors.Wrap(err, "")
+	}
+
+	user, err := get(txn, userID)
+	if err != nil {
+		return global, errors.Wrap(err, "")
+	}
+
+	// The user's limits are applied over the global ones, Unlimited on
+	// either comes out as zero.
+	l := Limits{ID: userID}
+	for _, src := range []Limits{global, user} {
+		override(&l.MinAmount, src.MinAmount)
+		override(&l.MaxAmount, src.MaxAmount)
+		for _, w := range []struct{ dst, src *Window }{
+			{&l.Daily, &src.Daily},
+			{&l.Weekly, &src.Weekly},
+			{&l.Monthly, &src.Monthly},
+		} {
+			override(&w.dst.Deposit, w.src.Deposit)
+			override(&w.dst.Withdraw, w.src.Withdraw)
+			override(&w.dst.Count, w.src.Count)
+		}
+	}
+
+	return l, nil
+}
+
+func get(txn *memdb.Txn, id string) (Limits, error) {
+	raw, err := txn.First("limit", "id", id)
+	if err != nil {
+		return Limits{}, errors.Wrap(err, "txn.First")
+	}
+	if raw == nil {
+		return Limits{}, nil
+	}
+
+	l, ok := raw.(Limits)
+	if !ok {
+		return Limits{}, errors.New("couldn't type assert limits")
+	}
+
+	return l, nil
+}
+
+// override sets dst to src when src is a limit, or lifts it when src is
+// Unlimited.
+func override(dst *int64, src int64) {
+	switch {
+	case src == Unlimited:
+		*dst = 0
+	case src > 0:
+		*dst = src
+	}
+}
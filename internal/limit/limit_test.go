@@ -0,0 +1,182 @@
+package limit_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/limit"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var (
+	ctx    context.Context
+	userID string
+)
+
+func TestLimit(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	var err error
+	userID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+
+	t.Run("limitOverride", limitOverride)
+	t.Run("limitMaxAmount", limitMaxAmount)
+	t.Run("limitDailyDeposit", limitDailyDeposit)
+	t.Run("limitDailyCount", limitDailyCount)
+	t.Run("limitWindowPassed", limitWindowPassed)
+	t.Run("limitUnlimited", limitUnlimited)
+	t.Run("limitReversal", limitReversal)
+	t.Run("limitUserInitiatedOnly", limitUserInitiatedOnly)
+}
+
+func insertUser(t *testing.T, l limit.Limits) string {
+	id, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Sam"})
+	assert.NoError(t, err)
+
+	l.ID = id
+	err = limit.Set(ctx, test.MasterDB, l)
+	assert.NoError(t, err)
+
+	return id
+}
+
+func limitOverride(t *testing.T) {
+	err := limit.Set(ctx, test.MasterDB, limit.Limits{
+		ID:        limit.Global,
+		MaxAmount: 1000,
+		Daily:     limit.Window{Deposit: 1500, Count: 3},
+	})
+	assert.NoError(t, err)
+
+	err = limit.Set(ctx, test.MasterDB, limit.Limits{
+		ID:    userID,
+		Daily: limit.Window{Deposit: 2000},
+	})
+	assert.NoError(t, err)
+
+	l, err := limit.Get(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), l.MaxAmount, "should inherit global limit")
+	assert.Equal(t, int64(2000), l.Daily.Deposit, "should be overridden")
+	assert.Equal(t, int64(3), l.Daily.Count, "should inherit global limit")
+}
+
+func limitMaxAmount(t *testing.T) {
+	_, err := user.DepositByID(ctx, test.MasterDB, userID, 1001)
+	assert.Equal(t, limit.ErrLimitExceeded, errors.Cause(err))
+}
+
+func limitDailyDeposit(t *testing.T) {
+	_, err := user.DepositByID(ctx, test.MasterDB, userID, 1000)
+	assert.NoError(t, err)
+
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, 1000)
+	assert.NoError(t, err)
+
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, 1)
+	assert.Equal(t, limit.ErrLimitExceeded, errors.Cause(err))
+
+	balance, err := user.GetBalanceByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000), balance, "rejected deposit shouldn't change balance")
+}
+
+func limitDailyCount(t *testing.T) {
+	_, err := user.WithdrawByID(ctx, test.MasterDB, userID, 100)
+	assert.NoError(t, err)
+
+	_, err = user.WithdrawByID(ctx, test.MasterDB, userID, 100)
+	assert.Equal(t, limit.ErrLimitExceeded, errors.Cause(err))
+}
+
+func limitWindowPassed(t *testing.T) {
+	txn := test.MasterDB.Txn(ctx, false)
+	defer txn.Abort()
+
+	err := limit.Check(txn, userID, ledger.TypeDeposit, 100, time.Now().Add(25*time.Hour))
+	assert.NoError(t, err)
+}
+
+func limitUnlimited(t *testing.T) {
+	id := insertUser(t, limit.Limits{
+		MaxAmount: limit.Unlimited,
+		Daily:     limit.Window{Deposit: limit.Unlimited, Count: limit.Unlimited},
+	})
+
+	l, err := limit.Get(ctx, test.MasterDB, id)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), l.MaxAmount, "should lift global limit")
+	assert.Equal(t, int64(0), l.Daily.Count, "should lift global limit")
+
+	_, err = user.DepositByID(ctx, test.MasterDB, id, 5000)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = user.WithdrawByID(ctx, test.MasterDB, id, 100)
+		assert.NoError(t, err)
+	}
+}
+
+func limitReversal(t *testing.T) {
+	id := insertUser(t, limit.Limits{
+		Daily: limit.Window{Deposit: 1000, Count: limit.Unlimited},
+	})
+
+	_, err := user.DepositByID(ctx, test.MasterDB, id, 1000)
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, id, 100)
+	assert.Equal(t, limit.ErrLimitExceeded, errors.Cause(err))
+
+	ts, err := user.ListTransactionsByID(ctx, test.MasterDB, id)
+	assert.NoError(t, err)
+	_, err = user.ReverseTransaction(ctx, test.MasterDB, ts[0].ID, 400)
+	assert.NoError(t, err)
+
+	_, err = user.DepositByID(ctx, test.MasterDB, id, 400)
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, id, 100)
+	assert.Equal(t, limit.ErrLimitExceeded, errors.Cause(err))
+}
+
+func limitUserInitiatedOnly(t *testing.T) {
+	id := insertUser(t, limit.Limits{})
+
+	_, err := user.DepositByID(ctx, test.MasterDB, id, 1000)
+	assert.NoError(t, err)
+
+	// captures aren't counted
+	for i := 0; i < 3; i++ {
+		h, err := user.CreateHold(ctx, test.MasterDB, id, 100, 0, "")
+		assert.NoError(t, err)
+		_, err = user.CaptureHold(ctx, test.MasterDB, h.ID, 0)
+		assert.NoError(t, err)
+	}
+
+	_, err = user.WithdrawByID(ctx, test.MasterDB, id, 100)
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, id, 100)
+	assert.NoError(t, err)
+
+	_, err = user.WithdrawByID(ctx, test.MasterDB, id, 100)
+	assert.Equal(t, limit.ErrLimitExceeded, errors.Cause(err))
+}
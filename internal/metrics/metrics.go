@@ -0,0 +1,129 @@
+// Package metrics holds the Prometheus collectors of the service. Packages
+// report through the functions here so none of them depends on the client
+// library directly.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Operation outcomes.
+const (
+	OutcomeOK                = "ok"
+	OutcomeInsufficientFunds = "insufficient_funds"
+	OutcomeRejected          = "rejected"
+	OutcomeError             = "error"
+)
+
+// Registry has every collector of the service.
+var Registry = prometheus.NewRegistry()
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time spent handling HTTP requests, by route, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	websocketConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "websocket_connections",
+		Help: "Open websocket connections, by topic.",
+	}, []string{"topic"})
+
+	websocketMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_messages_total",
+		Help: "Messages pushed to websocket clients, by topic.",
+	}, []string{"topic"})
+
+	operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallet_operations_total",
+		Help: "Wallet operations, by operation and outcome.",
+	}, []string{"op", "outcome"})
+
+	volume = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallet_volume_total",
+		Help: "Amount moved by successful wallet operations, by operation.",
+	}, []string{"op"})
+
+	insufficientFunds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallet_insufficient_funds_total",
+		Help: "Operations rejected for insufficient funds, by operation.",
+	}, []string{"op"})
+
+	txnDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "memdb_write_txn_duration_seconds",
+		Help:    "Time committed memdb write transactions were open.",
+		Buckets: []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5},
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requests,
+		requestDuration,
+		websocketConnections,
+		websocketMessages,
+		operations,
+		volume,
+		insufficientFunds,
+		txnDuration,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a handled request.
+func ObserveRequest(route, method string, status int, d time.Duration) {
+	s := strconv.Itoa(status)
+	requests.WithLabelValues(route, method, s).Inc()
+	requestDuration.WithLabelValues(route, method, s).Observe(d.Seconds())
+}
+
+// WebsocketOpened records a connection to topic and returns the function to
+// call when it's closed.
+func WebsocketOpened(topic string) func() {
+	g := websocketConnections.WithLabelValues(topic)
+	g.Inc()
+	return g.Dec
+}
+
+// WebsocketMessage records a message pushed on topic.
+func WebsocketMessage(topic string) {
+	websocketMessages.WithLabelValues(topic).Inc()
+}
+
+// Operation records a wallet operation and, when it succeeded, the amount
+// it moved.
+func Operation(op, outcome string, amount int64) {
+	operations.WithLabelValues(op, outcome).Inc()
+
+	if outcome == OutcomeOK {
+		volume.WithLabelValues(op).Add(float64(amount))
+	}
+}
+
+// InsufficientFunds records an operation rejected for insufficient funds.
+func InsufficientFunds(op string) {
+	insufficientFunds.WithLabelValues(op).Inc()
+}
+
+// ObserveTxn records how long a memdb write transaction was open.
+func ObserveTxn(d time.Duration) {
+	txnDuration.Observe(d.Seconds())
+}
@@ -0,0 +1,229 @@
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Checks a discrepancy can come from.
+const (
+	// CheckUnbalanced is a transaction whose postings don't sum up to zero.
+	CheckUnbalanced = "unbalanced_transaction"
+	// CheckBalance is an account whose stored balance isn't the sum of its
+	// ledger postings.
+	CheckBalance = "balance_mismatch"
+	// CheckHeld is a user whose held amount isn't the sum of the active
+	// holds.
+	CheckHeld = "held_mismatch"
+	// CheckNegative is a balance below zero on an account that can't go
+	// negative.
+	CheckNegative = "negative_balance"
+)
+
+var (
+	ErrNoReport = errors.New("no reconciliation report yet")
+)
+
+// Discrepancy is a single invariant that doesn't hold. Expected is what the
+// ledger or the invariant says, Actual what's stored.
+type Discrepancy struct {
+	Check         string `json:"check"`
+	AccountID     string `json:"account_id,omitempty"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	Expected      int64  `json:"expected"`
+	Actual        int64  `json:"actual"`
+}
+
+// Report is the result of a reconciliation run. Debits and Credits are the
+// totals over every posting in the ledger, they're equal when the books
+// balance.
+type Report struct {
+	ID            string        `json:"id"`
+	Seq           uint64        `json:"seq"`
+	StartedAt     time.Time     `json:"started_at"`
+	FinishedAt    time.Time     `json:"finished_at"`
+	Transactions  int           `json:"transactions"`
+	Accounts      int           `json:"accounts"`
+	Debits        int64         `json:"debits"`
+	Credits       int64         `json:"credits"`
+	Balanced      bool          `json:"balanced"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Run checks the ledger against the stored balances as of now and stores the
+// report. The checks all read the same snapshot of the database.
+func Run(ctx context.Context, dbConn *db.DB, now time.Time) (*Report, error) {
+	txn := dbConn.Txn(ctx, false)
+	r, err := check(txn)
+	txn.Abort()
+	if err != nil {
+		return nil, err
+	}
+
+	r.ID = uuid.New().String()
+	r.StartedAt = now
+	r.FinishedAt = time.Now()
+
+	txn = dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	last, err := last(txn)
+	if err != nil {
+		return nil, err
+	}
+	r.Seq = last.Seq + 1
+
+	if err := txn.Insert("reconciliation", r); err != nil {
+		return nil, errors.Wrap(err, "txn.Insert")
+	}
+
+	txn.Commit()
+
+	return &r, nil
+}
+
+// Last returns the report of the latest run, ErrNoReport if it never ran.
+func Last(ctx context.Context, dbConn *db.DB) (*Report, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	r, err := last(txn)
+	if err != nil {
+		return nil, err
+	}
+	if r.ID == "" {
+		return nil, ErrNoReport
+	}
+
+	return &r, nil
+}
+
+func last(txn *memdb.Txn) (Report, error) {
+	raw, err := txn.Last("reconciliation", "seq")
+	if err != nil {
+		return Report{}, errors.Wrap(err, "txn.Last")
+	}
+	if raw == nil {
+		return Report{}, nil
+	}
+
+	r, ok := raw.(Report)
+	if !ok {
+		return Report{}, errors.New("couldn't type assert reconciliation report")
+	}
+
+	return r, nil
+}
+
+func check(txn *memdb.Txn) (Report, error) {
+	r := Report{Discrepancies: []Discrepancy{}}
+
+	// Sum up the ledger per account.
+	sums := map[string]int64{}
+
+	it, err := txn.Get("transaction", "seq")
+	if err != nil {
+		return r, errors.Wrap(err, "txn.Get")
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		t, ok := obj.(ledger.Transaction)
+		if !ok {
+			return r, errors.New("couldn't type assert transaction")
+		}
+		r.Transactions++
+
+		var sum int64
+		for _, p := range t.Postings {
+			sums[p.AccountID] += p.Amount
+			sum += p.Amount
+			if p.Amount < 0 {
+				r.Debits -= p.Amount
+			} else {
+				r.Credits += p.Amount
+			}
+		}
+		if sum != 0 {
+			r.Discrepancies = append(r.Discrepancies, Discrepancy{
+				Check:         CheckUnbalanced,
+				TransactionID: t.ID,
+				Expected:      0,
+				Actual:        sum,
+			})
+		}
+	}
+
+	// Sum up the active holds per user.
+	held := map[string]int64{}
+
+	it, err = txn.Get("hold", "status", user.HoldActive)
+	if err != nil {
+		return r, errors.Wrap(err, "txn.Get")
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		h, ok := obj.(user.Hold)
+		if !ok {
+			return r, errors.New("couldn't type assert hold")
+		}
+		held[h.UserID] += h.Amount
+	}
+
+	// Compare them with what's stored on every user, deleted ones included.
+	it, err = txn.Get("user", "id")
+	if err != nil {
+		return r, errors.Wrap(err, "txn.Get")
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		u, ok := obj.(user.User)
+		if !ok {
+			return r, errors.New("couldn't type assert user")
+		}
+		r.Accounts++
+
+		r.compare(CheckBalance, u.ID, sums[u.ID], u.Balance)
+		r.compare(CheckBalance, ledger.BonusAccount(u.ID), sums[ledger.BonusAccount(u.ID)], u.Bonus)
+		r.compare(CheckHeld, u.ID, held[u.ID], u.Held)
+
+		r.negative(u.ID, u.Available())
+		r.negative(ledger.BonusAccount(u.ID), u.Bonus)
+	}
+
+	// The house and the outside world can go negative, fee revenue can't.
+	r.negative(ledger.Revenue, sums[ledger.Revenue])
+
+	r.Balanced = r.Debits == r.Credits && len(r.Discrepancies) == 0
+
+	return r, nil
+}
+
+func (r *Report) compare(check, accountID string, expected, actual int64) {
+	if expected == actual {
+		return
+	}
+
+	r.Discrepancies = append(r.Discrepancies, Discrepancy{
+		Check:     check,
+		AccountID: accountID,
+		Expected:  expected,
+		Actual:    actual,
+	})
+}
+
+func (r *Report) negative(accountID string, balance int64) {
+	if balance >= 0 {
+		return
+	}
+
+	r.Discrepancies = append(r.Discrepancies, Discrepancy{
+		Check:     CheckNegative,
+		AccountID: accountID,
+		Expected:  0,
+		Actual:    balance,
+	})
+}
@@ -0,0 +1,93 @@
+package reconcile_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/reconcile"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var (
+	ctx    context.Context
+	userID string
+)
+
+func TestReconcile(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	var err error
+	userID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+
+	t.Run("reconcileNoReport", reconcileNoReport)
+	t.Run("reconcileBalanced", reconcileBalanced)
+	t.Run("reconcileMismatch", reconcileMismatch)
+}
+
+func reconcileNoReport(t *testing.T) {
+	_, err := reconcile.Last(ctx, test.MasterDB)
+	assert.Equal(t, reconcile.ErrNoReport, err)
+}
+
+func reconcileBalanced(t *testing.T) {
+	toID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Kim"})
+	assert.NoError(t, err)
+
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, 1000)
+	assert.NoError(t, err)
+	_, err = user.TransferByID(ctx, test.MasterDB, userID, toID, 300)
+	assert.NoError(t, err)
+	_, err = user.CreateHold(ctx, test.MasterDB, userID, 200, time.Hour, "")
+	assert.NoError(t, err)
+
+	r, err := reconcile.Run(ctx, test.MasterDB, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, r.Balanced)
+	assert.Equal(t, 0, len(r.Discrepancies))
+	assert.Equal(t, r.Debits, r.Credits)
+
+	last, err := reconcile.Last(ctx, test.MasterDB)
+	assert.NoError(t, err)
+	assert.Equal(t, r.ID, last.ID)
+}
+
+func reconcileMismatch(t *testing.T) {
+	// change the balance behind the ledger's back
+	txn := test.MasterDB.Txn(tests.Context(), true)
+	raw, err := txn.First("user", "id", userID)
+	assert.NoError(t, err)
+	u := raw.(user.User)
+	u.Balance = u.Balance + 50
+	err = txn.Insert("user", u)
+	assert.NoError(t, err)
+	txn.Commit()
+
+	r, err := reconcile.Run(ctx, test.MasterDB, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, r.Balanced)
+	assert.Equal(t, 1, len(r.Discrepancies))
+	assert.Equal(t, reconcile.CheckBalance, r.Discrepancies[0].Check)
+	assert.Equal(t, userID, r.Discrepancies[0].AccountID)
+	assert.Equal(t, int64(700), r.Discrepancies[0].Expected)
+	assert.Equal(t, int64(750), r.Discrepancies[0].Actual)
+	assert.Equal(t, uint64(2), r.Seq)
+}
@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Roles a principal can have.
+const (
+	RoleAdmin = "admin"
+)
+
+var ErrInvalidTokens = errors.New("tokens must be name:role:token entries separated by commas")
+
+// Principal is who a request was authenticated as.
+type Principal struct {
+	Name string
+	Role string
+}
+
+// Tokens maps the bearer tokens clients authenticate with to who they are.
+// Only the hashes of the tokens are kept.
+type Tokens map[string]Principal
+
+// ParseTokens reads comma separated name:role:token entries.
+func ParseTokens(s string) (Tokens, error) {
+	tokens := Tokens{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, ErrInvalidTokens
+		}
+		tokens[tokenHash(parts[2])] = Principal{Name: parts[0], Role: parts[1]}
+	}
+
+	return tokens, nil
+}
+
+// lookup returns who token belongs to.
+func (t Tokens) lookup(token string) (Principal, bool) {
+	p, ok := t[tokenHash(token)]
+	return p, ok
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
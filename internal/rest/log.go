@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Log formats.
+const (
+	LogText = "text"
+	LogJSON = "json"
+)
+
+var ErrInvalidLogFormat = errors.New("log format must be text or json")
+
+// logger is what the middlewares log with, see SetLogger.
+var logger = slog.New(contextHandler{slog.NewTextHandler(os.Stderr, nil)})
+
+// NewLogger creates a logger writing lines in format from level up. Lines
+// logged with the context of a request carry its trace ID, route, user ID
+// and status.
+func NewLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case LogText:
+		h = slog.NewTextHandler(w, opts)
+	case LogJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, ErrInvalidLogFormat
+	}
+
+	return slog.New(contextHandler{h}), nil
+}
+
+// SetLogger makes the middlewares log with l.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
+// Logger returns the logger set with SetLogger, for middlewares of other
+// packages.
+func Logger() *slog.Logger {
+	return logger
+}
+
+// contextHandler adds the values of the request in the context to every
+// record.
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle implements slog.Handler.
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if v, ok := ctx.Value(KeyValues).(*Values); ok {
+		r.AddAttrs(slog.String("trace_id", v.TraceID), slog.String("route", v.Route))
+		if v.UserID != "" {
+			r.AddAttrs(slog.String("user_id", v.UserID))
+		}
+		if v.StatusCode != 0 {
+			r.AddAttrs(slog.Int("status", v.StatusCode))
+		}
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
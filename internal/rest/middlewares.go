@@ -1,59 +1,54 @@
 package rest
 
 import (
+	"bytes"
 	"context"
-	"log"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
-	"os"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/metrics"
 )
 
-var logStdErr *log.Logger
-
-func init() {
-	logStdErr = log.New(os.Stderr, "", log.LstdFlags)
-	logStdErr.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
-}
-
 // ErrorHandler for catching and responding errors.
 func ErrorHandlerMiddleware(next Handler) Handler {
 	// Create the handler that will be attached in the middleware chain.
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
 
-		v := ctx.Value(KeyValues).(*Values)
-
 		// In the event of a panic, we want to capture it here so we can send an
 		// error down the stack.
 		defer func() {
 
 			if r := recover(); r != nil {
 
-				// Log the panic.
-				logStdErr.Printf("%s : ERROR : Panic Caught : %s\n", v.TraceID, r)
-
 				// Respond with the error.
 				errorHandler(ctx, w, errors.New("unhandled"))
 
-				// Print out the stack.
-				logStdErr.Printf("%s : ERROR : Stacktrace\n%s\n", v.TraceID, debug.Stack())
+				// Log the panic with the stack.
+				logger.ErrorContext(ctx, "panic caught", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
 			}
 		}()
 
 		// TODO: check that no patient sensitive information is leaked
 		if err := next(ctx, w, r, params); err != nil {
-			if errors.Cause(err) != ErrNotFound {
-
-				// Log the error.
-				logStdErr.Printf("%s : ERROR : %+v\n", v.TraceID, err)
-			}
-
 			// Respond with the error.
 			errorHandler(ctx, w, err)
 
+			// Log the error once the status it got is known.
+			if !isExpected(err) {
+				logger.ErrorContext(ctx, "request failed", "error", fmt.Sprintf("%+v", err))
+			}
+
 			// The error has been handled so we can stop propigating it.
 			return nil
 		}
@@ -62,8 +57,8 @@ func ErrorHandlerMiddleware(next Handler) Handler {
 	}
 }
 
-// RequestLogger writes some information about the request to the logs in
-// the format: TraceID : (200) GET /foo -> IP ADDR (latency)
+// RequestLogger writes some information about the request to the logs, the
+// method, path, remote address and latency next to the request values.
 func RequestLoggerMiddleware(next Handler) Handler {
 	// Wrap this handler around the next one provided.
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
@@ -71,11 +66,11 @@ func RequestLoggerMiddleware(next Handler) Handler {
 
 		next(ctx, w, r, params)
 
-		log.Printf("%s : (%d) : %s %s -> %s (%s)",
-			v.TraceID,
-			v.StatusCode,
-			r.Method, r.URL.Path,
-			r.RemoteAddr, time.Since(v.Now),
+		logger.InfoContext(ctx, "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"remote_addr", r.RemoteAddr,
+			"latency", time.Since(v.Now),
 		)
 
 		// This is the top of the food chain. At this point all error
@@ -84,6 +79,161 @@ func RequestLoggerMiddleware(next Handler) Handler {
 	}
 }
 
+// AuthorizationHeader carries the bearer token of the request.
+const AuthorizationHeader = "Authorization"
+
+// TokenMiddleware rejects requests without a bearer token of tokens with
+// ErrUnauthorized and those of principals without role with ErrForbidden.
+// Requests are always rejected when there are no tokens.
+func TokenMiddleware(tokens Tokens, role string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			auth := r.Header.Get(AuthorizationHeader)
+			if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+				return ErrUnauthorized
+			}
+
+			p, ok := tokens.lookup(strings.TrimSpace(auth[7:]))
+			if !ok {
+				return ErrUnauthorized
+			}
+
+			v := ctx.Value(KeyValues).(*Values)
+			v.Actor = p.Name
+			v.AuthMethod = AuthToken
+
+			if p.Role != role {
+				return ErrForbidden
+			}
+
+			return next(ctx, w, r, params)
+		}
+	}
+}
+
+// MetricsMiddleware counts requests and records their latency by route,
+// method and status. It has to wrap ErrorHandlerMiddleware to see the status
+// errors are responded with.
+func MetricsMiddleware(next Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		v := ctx.Value(KeyValues).(*Values)
+
+		err := next(ctx, w, r, params)
+
+		metrics.ObserveRequest(routeOf(r), r.Method, v.StatusCode, time.Since(v.Now))
+
+		return err
+	}
+}
+
+// routeOf returns the path template of the route r matched, so paths with
+// IDs don't each get their own metrics.
+func routeOf(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+
+	tpl, _ := route.GetPathTemplate()
+	return tpl
+}
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the request
+// timestamp, a dot and the body, SignatureTimestampHeader the timestamp in
+// unix seconds.
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+)
+
+// SignatureActor is the actor of requests signed with the shared secret.
+const SignatureActor = "signed-client"
+
+// SignatureMaxBody is the largest body a signed request can have.
+const SignatureMaxBody = 1 << 20
+
+// SignatureMiddleware rejects requests that aren't signed with secret or
+// were signed more than window away from now, so a captured request can't
+// be replayed later. Requests are always rejected when there's no secret.
+func SignatureMiddleware(secret string, window time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			if secret == "" {
+				return ErrUnauthorized
+			}
+
+			v := ctx.Value(KeyValues).(*Values)
+
+			ts := r.Header.Get(SignatureTimestampHeader)
+			unix, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return ErrUnauthorized
+			}
+			if d := v.Now.Sub(time.Unix(unix, 0)); d > window || d < -window {
+				return ErrUnauthorized
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, SignatureMaxBody))
+			if err != nil {
+				if _, ok := err.(*http.MaxBytesError); ok {
+					return NewResponseError(err, http.StatusRequestEntityTooLarge)
+				}
+				return errors.Wrap(err, "")
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			sig, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+			if err != nil {
+				return ErrUnauthorized
+			}
+
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(ts + "."))
+			mac.Write(body)
+			if !hmac.Equal(sig, mac.Sum(nil)) {
+				return ErrUnauthorized
+			}
+
+			v.Actor = SignatureActor
+			v.AuthMethod = AuthHMAC
+
+			return next(ctx, w, r, params)
+		}
+	}
+}
+
+// Headers of the responses on deprecated routes.
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+	LinkHeader        = "Link"
+)
+
+// deprecationMiddleware marks the responses of routes under prefix as
+// deprecated since d.Since, or since it's created when that's zero, as in
+// RFC 9745 and RFC 8594. The Link header has the same path under successor.
+func deprecationMiddleware(d Deprecation, prefix, successor string) Middleware {
+	since := d.Since
+	if since.IsZero() {
+		since = time.Now()
+	}
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			w.Header().Set(DeprecationHeader, deprecation)
+			if !d.Sunset.IsZero() {
+				w.Header().Set(SunsetHeader, d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				w.Header().Set(LinkHeader, fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor, strings.TrimPrefix(r.URL.Path, prefix)))
+			}
+
+			return next(ctx, w, r, params)
+		}
+	}
+}
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -99,6 +249,24 @@ func websocketMiddleware(next Handler) Handler {
 
 		ctx = context.WithValue(ctx, WebsocketConnection, wsConn)
 
-		return next(ctx, w, r, params)
+		// The route is the topic the connection is subscribed to.
+		topic := routeOf(r)
+		ctx = context.WithValue(ctx, WebsocketTopic, topic)
+		defer metrics.WebsocketOpened(topic)()
+
+		v := ctx.Value(KeyValues).(*Values)
+		v.StatusCode = http.StatusSwitchingProtocols
+
+		// The connection is hijacked, the errors are told about over it
+		// rather than by ErrorHandlerMiddleware.
+		if err := next(ctx, w, r, params); err != nil {
+			errorHandler(ctx, w, err)
+
+			if !isExpected(err) {
+				logger.ErrorContext(ctx, "request failed", "error", fmt.Sprintf("%+v", err))
+			}
+		}
+
+		return nil
 	}
 }
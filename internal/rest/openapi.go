@@ -0,0 +1,559 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification Spec follows.
+const OpenAPIVersion = "3.0.3"
+
+// Ruled is a request or response body listing the rules Validate checks.
+// Rules is called on a zero value by the spec generator, the rules show as
+// the constraints of the fields in the schema of the body.
+type Ruled interface {
+	Rules() []*validation.FieldRules
+}
+
+// Param is a query or header parameter of a route, In says which and is
+// query when it's empty. Type is a JSON schema type, string when it's empty.
+type Param struct {
+	Name        string
+	In          string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// Doc describes a route in the OpenAPI spec. Request and Response are values
+// of the types of the JSON bodies, nil when there's none. Consumes lists the
+// content types of a request body that isn't JSON. Status is the status of
+// a successful response, 200 when it's zero. ContentType is that of the
+// response, JSON when it's empty.
+type Doc struct {
+	Summary     string
+	Tag         string
+	Params      []Param
+	Request     interface{}
+	Consumes    []string
+	Response    interface{}
+	Status      int
+	ContentType string
+}
+
+// Spec is an OpenAPI document.
+type Spec struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+// Info has the title and version of the API.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components has the schemas operations refer to.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation is a route of the spec.
+type Operation struct {
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+}
+
+// Parameter is a path or query parameter of an operation.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body an operation takes.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response an operation gives.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType has the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON schema the generator uses.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// Describe adds the docs of routes, keyed by verb and path as in
+// "GET /api/v1/user/{userID}".
+func (a *App) Describe(docs map[string]Doc) {
+	if a.docs == nil {
+		a.docs = make(map[string]Doc, len(docs))
+	}
+	for k, d := range docs {
+		a.docs[k] = d
+	}
+}
+
+// Drift lists the routes registered without a doc and the docs of routes
+// that aren't registered, empty when the spec covers the routes exactly.
+func (a *App) Drift() []string {
+	var drift []string
+
+	registered := make(map[string]bool, len(a.routes))
+	for _, rt := range a.routes {
+		if rt.aliasOf != "" {
+			continue
+		}
+		registered[rt.key] = true
+		if _, ok := a.docs[rt.key]; !ok {
+			drift = append(drift, "route without a doc: "+rt.key)
+		}
+	}
+	for k := range a.docs {
+		if !registered[k] {
+			drift = append(drift, "doc of an unregistered route: "+k)
+		}
+	}
+	sort.Strings(drift)
+
+	return drift
+}
+
+// OpenAPI generates the spec of the documented routes. Aliases of routes are
+// described as deprecated copies of them.
+func (a *App) OpenAPI(title, version string) *Spec {
+	g := generator{schemas: map[string]*Schema{}}
+	errSchema := g.schemaOf(reflect.TypeOf(JSONError{}))
+	problemSchema := g.schemaOf(reflect.TypeOf(Problem{}))
+
+	// Clients match the codes, list them all.
+	var codes []interface{}
+	for _, c := range Codes() {
+		codes = append(codes, c)
+	}
+	for _, sc := range []*Schema{errSchema, problemSchema} {
+		g.schemas[strings.TrimPrefix(sc.Ref, "#/components/schemas/")].Properties["code"].Enum = codes
+	}
+
+	spec := Spec{
+		OpenAPI:    OpenAPIVersion,
+		Info:       Info{Title: title, Version: version},
+		Paths:      map[string]map[string]*Operation{},
+		Components: Components{Schemas: g.schemas},
+	}
+
+	for _, rt := range a.routes {
+		k := rt.key
+		if rt.aliasOf != "" {
+			k = rt.aliasOf
+		}
+		d, ok := a.docs[k]
+		if !ok {
+			continue
+		}
+		verb, p := splitRoute(rt.key)
+
+		op := Operation{
+			Summary:    d.Summary,
+			Deprecated: rt.aliasOf != "",
+			Responses:  map[string]Response{},
+		}
+		if d.Tag != "" {
+			op.Tags = []string{d.Tag}
+		}
+		for _, name := range pathParams(p) {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+		for _, q := range d.Params {
+			in, typ := q.In, q.Type
+			if in == "" {
+				in = "query"
+			}
+			if typ == "" {
+				typ = "string"
+			}
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:        q.Name,
+				In:          in,
+				Description: q.Description,
+				Required:    q.Required,
+				Schema:      &Schema{Type: typ},
+			})
+		}
+		switch {
+		case d.Request != nil:
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  jsonContent(g.schemaOf(reflect.TypeOf(d.Request))),
+			}
+		case len(d.Consumes) > 0:
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{},
+			}
+			for _, ct := range d.Consumes {
+				op.RequestBody.Content[ct] = MediaType{Schema: &Schema{Type: "string"}}
+			}
+		}
+
+		status := d.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		res := Response{Description: http.StatusText(status)}
+		switch {
+		case d.ContentType != "":
+			res.Content = map[string]MediaType{d.ContentType: {Schema: &Schema{Type: "string"}}}
+			if d.Response != nil {
+				res.Content["application/json"] = MediaType{Schema: g.schemaOf(reflect.TypeOf(d.Response))}
+			}
+		case d.Response != nil:
+			res.Content = jsonContent(g.schemaOf(reflect.TypeOf(d.Response)))
+		}
+		op.Responses[strconv.Itoa(status)] = res
+		op.Responses["default"] = Response{
+			Description: "Error",
+			Content: map[string]MediaType{
+				"application/json": {Schema: errSchema},
+				ProblemContentType: {Schema: problemSchema},
+			},
+		}
+
+		if spec.Paths[p] == nil {
+			spec.Paths[p] = map[string]*Operation{}
+		}
+		spec.Paths[p][strings.ToLower(verb)] = &op
+	}
+
+	return &spec
+}
+
+// routeKey is how routes and their docs are keyed.
+func routeKey(verb, path string) string {
+	return verb + " " + path
+}
+
+func splitRoute(k string) (string, string) {
+	i := strings.IndexByte(k, ' ')
+	return k[:i], k[i+1:]
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// pathParams returns the names of the parameters in the path template.
+func pathParams(p string) []string {
+	var names []string
+	for _, m := range pathParam.FindAllStringSubmatch(p, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	ruledType      = reflect.TypeOf((*Ruled)(nil)).Elem()
+)
+
+// generator builds the schemas of Go types, named structs once in schemas.
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schemaOf(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := g.schemas[name]; !ok {
+			// Take the name first so types referring to themselves end.
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &Schema{}
+}
+
+// structSchema has a property per field encoding/json encodes, with the
+// constraints of the rules of the type if it's Ruled.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+
+	// names has the property of each field by index.
+	names := map[int]string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(f.Type)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = g.schemaOf(f.Type)
+		names[i] = name
+	}
+
+	if reflect.PtrTo(t).Implements(ruledType) {
+		v := reflect.New(t)
+		for _, fr := range v.Interface().(Ruled).Rules() {
+			i, rules, ok := fieldRules(v.Elem(), fr)
+			if !ok {
+				continue
+			}
+			name, ok := names[i]
+			if !ok {
+				continue
+			}
+			for _, r := range rules {
+				if applyRule(s.Properties[name], r) && !contains(s.Required, name) {
+					s.Required = append(s.Required, name)
+				}
+			}
+		}
+	}
+
+	return &s
+}
+
+// fieldRules returns the index in the struct v of the field fr is of, and
+// its rules. The fields of fr are unexported so the rules are only read
+// through reflection.
+func fieldRules(v reflect.Value, fr *validation.FieldRules) (int, []reflect.Value, bool) {
+	frv := reflect.ValueOf(fr).Elem()
+	ptr := frv.FieldByName("fieldPtr").Elem()
+	if ptr.Kind() != reflect.Ptr {
+		return 0, nil, false
+	}
+
+	index := -1
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).UnsafeAddr() == ptr.Pointer() && v.Field(i).Type() == ptr.Type().Elem() {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return 0, nil, false
+	}
+
+	rv := frv.FieldByName("rules")
+	rules := make([]reflect.Value, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		if r := rv.Index(i).Elem(); r.Kind() == reflect.Ptr && !r.IsNil() {
+			rules = append(rules, r)
+		}
+	}
+
+	return index, rules, true
+}
+
+var (
+	required      = reflect.ValueOf(validation.Required).Pointer()
+	notNil        = reflect.ValueOf(validation.NotNil).Pointer()
+	thresholdRule = reflect.TypeOf(&validation.ThresholdRule{})
+	lengthRule    = reflect.TypeOf(&validation.LengthRule{})
+	inRule        = reflect.TypeOf(&validation.InRule{})
+	stringRule    = reflect.TypeOf(&validation.StringRule{})
+)
+
+// formats maps the messages of string rules to the formats they check.
+var formats = map[string]string{
+	"must be a valid email address": "email",
+	"must be a valid URL":           "uri",
+}
+
+// applyRule sets the constraint of the rule r on s and reports whether r
+// makes the field required. Rules the spec can't express, like those of
+// validation.By, are left out.
+func applyRule(s *Schema, r reflect.Value) bool {
+	switch r.Pointer() {
+	case required, notNil:
+		return true
+	}
+
+	e := r.Elem()
+	switch r.Type() {
+	case thresholdRule:
+		n, ok := number(e.FieldByName("threshold").Elem())
+		if !ok {
+			break
+		}
+		// The operators of ThresholdRule are greater than, greater or
+		// equal, less than and less or equal, in that order.
+		switch e.FieldByName("operator").Int() {
+		case 0:
+			s.Minimum, s.ExclusiveMinimum = &n, true
+		case 1:
+			s.Minimum = &n
+		case 2:
+			s.Maximum, s.ExclusiveMaximum = &n, true
+		case 3:
+			s.Maximum = &n
+		}
+
+	case lengthRule:
+		min, max := int(e.FieldByName("min").Int()), int(e.FieldByName("max").Int())
+		if s.Type == "array" {
+			if min > 0 {
+				s.MinItems = &min
+			}
+			if max > 0 {
+				s.MaxItems = &max
+			}
+			break
+		}
+		if min > 0 {
+			s.MinLength = &min
+		}
+		if max > 0 {
+			s.MaxLength = &max
+		}
+
+	case inRule:
+		elems := e.FieldByName("elements")
+		for i := 0; i < elems.Len(); i++ {
+			el := elems.Index(i).Elem()
+			switch el.Kind() {
+			case reflect.String:
+				s.Enum = append(s.Enum, el.String())
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				s.Enum = append(s.Enum, el.Int())
+			}
+		}
+
+	case stringRule:
+		msg := e.FieldByName("message").String()
+		if f, ok := formats[msg]; ok {
+			s.Format = f
+		} else {
+			s.Description = msg
+		}
+	}
+
+	return false
+}
+
+// number returns the numeric value of v, false if it isn't a number.
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
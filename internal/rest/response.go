@@ -6,7 +6,11 @@
 //		204 No Content   : StatusNoContent           : Call is success and returns no data.
 //		400 Bad Request  : StatusBadRequest          : Invalid post data (syntax or semantics).
 //		401 Unauthorized : StatusUnauthorized        : Authentication failure.
+//		402 Payment Req. : StatusPaymentRequired     : Not enough funds for the operation.
+//		403 Forbidden    : StatusForbidden           : Operation is not allowed.
 //		404 Not Found    : StatusNotFound            : Invalid URL or identifier.
+//		409 Conflict     : StatusConflict            : Operation conflicts with the entity's state.
+//		422 Unprocessable: StatusUnprocessableEntity : Valid request the business rules reject.
 //		500 Internal     : StatusInternalServerError : Application specific beyond scope of user.
 
 package rest
@@ -17,10 +21,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/metrics"
+	"github.com/timurguseynov/go-wallet-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Invalid describes a validation error belonging to a specific field.
@@ -62,12 +72,45 @@ func (re ResponseError) Error() string {
 	return re.Err.Error()
 }
 
-// JSONError is the response for errors that occur within the API.
+// JSONError is the response for errors that occur within the API. Code is
+// stable, clients should match it rather than the message.
 type JSONError struct {
 	Error  string       `json:"error"`
+	Code   string       `json:"code"`
 	Fields InvalidError `json:"fields,omitempty"`
 }
 
+// ProblemContentType is the media type of RFC 7807 problem details, errors
+// are responded as a Problem to the requests accepting it.
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix starts the type of every Problem, the rest is the code of
+// the error.
+const ProblemTypePrefix = "urn:go-wallet-api:problem:"
+
+// Problem is the RFC 7807 form of JSONError. Instance is the trace ID of the
+// request, Code and Fields are extensions with the same meaning as in
+// JSONError.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Fields   InvalidError `json:"fields,omitempty"`
+}
+
+// Codes of the errors of this package.
+const (
+	CodeUnauthorized = "UNAUTHORIZED"
+	CodeForbidden    = "FORBIDDEN"
+	CodeNotFound     = "NOT_FOUND"
+	CodeInvalidID    = "INVALID_ID"
+	CodeValidation   = "VALIDATION_FAILED"
+	CodeInternal     = "INTERNAL_ERROR"
+)
+
 var (
 
 	// ErrUnauthorized occurs when the call is not authorized.
@@ -92,54 +135,213 @@ var (
 	ErrCtxNoWebsocketConnection = errors.New("no websocket connection found in context")
 )
 
-// ErrorHandler handles all error responses for the API.
-func ErrorHandler(ctx context.Context, w http.ResponseWriter, err error) {
+// registration is how a domain error is responded with.
+type registration struct {
+	status int
+	code   string
+}
+
+// registrations holds the domain errors registered with RegisterError.
+var registrations = map[error]registration{}
+
+// RegisterError makes the error handlers respond with status and code
+// whenever the cause of an error is err. Packages define their domain errors
+// without knowing about HTTP, the application registers them once at startup.
+// Codes are part of the API and must not change once released.
+func RegisterError(err error, status int, code string) {
+	registrations[err] = registration{status: status, code: code}
+}
+
+// Codes returns every code the error handlers respond with, sorted.
+func Codes() []string {
+	codes := []string{CodeUnauthorized, CodeForbidden, CodeNotFound, CodeInvalidID, CodeValidation, CodeInternal}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		seen[c] = true
+	}
+	for _, r := range registrations {
+		if !seen[r.code] {
+			seen[r.code] = true
+			codes = append(codes, r.code)
+		}
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+// registered returns the registration of the cause of err.
+func registered(err error) (registration, bool) {
+	cause := errors.Cause(err)
+
+	// Errors like InvalidError can't be map keys and are never registered.
+	if !reflect.TypeOf(cause).Comparable() {
+		return registration{}, false
+	}
+
+	r, ok := registrations[cause]
+	return r, ok
+}
+
+// isExpected reports whether err is an outcome clients are told about rather
+// than a failure of the service.
+func isExpected(err error) bool {
+	if errors.Cause(err) == ErrNotFound {
+		return true
+	}
+	_, ok := registered(err)
+	return ok
+}
+
+// StatusOf returns the status ErrorHandler responds to err with, for
+// handlers that describe an error in a response of their own.
+func StatusOf(err error) int {
+	status, _ := describe(err)
+	return status
+}
+
+// CodeOf returns the code ErrorHandler responds to err with.
+func CodeOf(err error) string {
+	_, v := describe(err)
+	return v.Code
+}
+
+// MessageOf returns the error text ErrorHandler responds to err with, the
+// context it was wrapped with included.
+func MessageOf(err error) string {
+	_, v := describe(err)
+	return v.Error
+}
+
+// describe returns the status and the body err is responded with.
+func describe(err error) (int, JSONError) {
 	switch errors.Cause(err) {
 	case ErrNotFound:
-		RespondError(ctx, w, err, http.StatusNotFound)
-		return
-
+		return http.StatusNotFound, JSONError{Error: err.Error(), Code: CodeNotFound}
 	case ErrInvalidID:
-		RespondError(ctx, w, err, http.StatusBadRequest)
-		return
-
+		return http.StatusBadRequest, JSONError{Error: err.Error(), Code: CodeInvalidID}
 	case ErrValidation:
-		RespondError(ctx, w, err, http.StatusBadRequest)
-		return
-
+		return http.StatusBadRequest, JSONError{Error: err.Error(), Code: CodeValidation}
 	case ErrUnauthorized:
-		RespondError(ctx, w, err, http.StatusUnauthorized)
-		return
-
+		return http.StatusUnauthorized, JSONError{Error: err.Error(), Code: CodeUnauthorized}
 	case ErrForbidden:
-		RespondError(ctx, w, err, http.StatusForbidden)
-		return
+		return http.StatusForbidden, JSONError{Error: err.Error(), Code: CodeForbidden}
+	}
+
+	if r, ok := registered(err); ok {
+		return r.status, JSONError{Error: message(err), Code: r.code}
 	}
 
 	switch e := errors.Cause(err).(type) {
 	case InvalidError:
-		v := JSONError{
-			Error:  ErrValidation.Error(),
-			Fields: e,
-		}
-		Respond(ctx, w, v, http.StatusBadRequest)
-		return
+		return http.StatusBadRequest, JSONError{Error: ErrValidation.Error(), Code: CodeValidation, Fields: e}
 	case ResponseError:
-		RespondError(ctx, w, e.Err, e.Status)
+		code := statusCode(e.Status)
+		if r, ok := registered(e.Err); ok {
+			code = r.code
+		}
+		return e.Status, JSONError{Error: e.Err.Error(), Code: code}
+	}
+
+	return http.StatusInternalServerError, JSONError{Error: err.Error(), Code: CodeInternal}
+}
+
+// statusCode is the code of a ResponseError that wraps no registered error,
+// the status text in upper snake case like "BAD_REQUEST".
+func statusCode(status int) string {
+	if status == http.StatusInternalServerError {
+		return CodeInternal
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return CodeInternal
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// message returns the text of err without the empty prefixes left by
+// errors.Wrap(err, "").
+func message(err error) string {
+	return strings.TrimLeft(err.Error(), ": ")
+}
+
+// NewProblem describes the error v responded with status as a Problem about
+// the request of ctx.
+func NewProblem(ctx context.Context, v JSONError, status int) Problem {
+	p := Problem{
+		Type:   ProblemTypePrefix + strings.ToLower(strings.ReplaceAll(v.Code, "_", "-")),
+		Title:  title(v.Code),
+		Status: status,
+		Detail: v.Error,
+		Code:   v.Code,
+		Fields: v.Fields,
+	}
+	if values, ok := ctx.Value(KeyValues).(*Values); ok {
+		p.Instance = values.TraceID
+	}
+
+	return p
+}
+
+// title turns a code like "INSUFFICIENT_FUNDS" into "Insufficient funds".
+func title(code string) string {
+	t := strings.ToLower(strings.ReplaceAll(code, "_", " "))
+	if t == "" {
+		return t
+	}
+	return strings.ToUpper(t[:1]) + t[1:]
+}
+
+// acceptsProblem reports whether the Accept header of r lists
+// ProblemContentType.
+func acceptsProblem(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mr := range strings.Split(accept, ",") {
+			mt := strings.TrimSpace(strings.SplitN(mr, ";", 2)[0])
+			if strings.EqualFold(mt, ProblemContentType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wantsProblem reports whether the request of ctx accepts problem details.
+func wantsProblem(ctx context.Context) bool {
+	v, ok := ctx.Value(KeyValues).(*Values)
+	return ok && v.Problem
+}
+
+// ErrorHandler handles all error responses for the API.
+func ErrorHandler(ctx context.Context, w http.ResponseWriter, err error) {
+	status, v := describe(err)
+
+	if wantsProblem(ctx) {
+		RespondProblem(ctx, w, NewProblem(ctx, v, status))
 		return
 	}
 
-	RespondError(ctx, w, err, http.StatusInternalServerError)
+	Respond(ctx, w, v, status)
 }
 
 // RespondError sends JSON describing the error
 func RespondError(ctx context.Context, w http.ResponseWriter, err error, code int) {
-	Respond(ctx, w, JSONError{Error: err.Error()}, code)
+	Respond(ctx, w, JSONError{Error: err.Error(), Code: statusCode(code)}, code)
+}
+
+// RespondProblem sends the problem details p.
+func RespondProblem(ctx context.Context, w http.ResponseWriter, p Problem) {
+	respond(ctx, w, p, p.Status, ProblemContentType)
 }
 
 // Respond sends JSON to the client.
 // If code is StatusNoContent, v is expected to be nil.
 func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, code int) {
+	respond(ctx, w, data, code, "application/json")
+}
+
+// respond sends data as JSON of the content type.
+func respond(ctx context.Context, w http.ResponseWriter, data interface{}, code int, contentType string) {
 	// Set the status code for the request logger middleware.
 	v := ctx.Value(KeyValues).(*Values)
 	v.StatusCode = code
@@ -151,14 +353,14 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, code
 	}
 
 	// Set the content type.
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 
 	// Write the status code to the response and context.
 	w.WriteHeader(code)
 	// Marshal the data into a JSON string.
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		logStdErr.Printf("%s : Respond %v Marshalling JSON response\n", v.TraceID, err)
+		logger.ErrorContext(ctx, "marshalling JSON response", "error", err)
 		jsonData = []byte("{}")
 	}
 
@@ -166,43 +368,31 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, code
 	io.WriteString(w, string(jsonData))
 }
 
-func WebsocketErrorHandler(ctx context.Context, err error) {
-	switch errors.Cause(err) {
-	case ErrNotFound:
-		websocketRespondError(ctx, err, websocket.CloseInternalServerErr)
-		return
-
-	case ErrInvalidID:
-		websocketRespondError(ctx, err, websocket.CloseInternalServerErr)
-		return
-
-	case ErrValidation:
-		websocketRespondError(ctx, err, websocket.CloseInternalServerErr)
-		return
+// RespondFile sends data as a download named filename.
+func RespondFile(ctx context.Context, w http.ResponseWriter, data []byte, contentType, filename string, code int) {
+	// Set the status code for the request logger middleware.
+	v := ctx.Value(KeyValues).(*Values)
+	v.StatusCode = code
 
-	case ErrUnauthorized:
-		websocketRespondError(ctx, err, websocket.CloseInternalServerErr)
-		return
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(code)
+	w.Write(data)
+}
 
-	case ErrForbidden:
-		websocketRespondError(ctx, err, websocket.CloseInternalServerErr)
-		return
-	}
+// WebsocketErrorHandler handles the errors of websocket handlers with the
+// codes ErrorHandler responds with. Validation errors are pushed, the others
+// close the connection. Problems are never sent, they don't fit in the 123
+// bytes of a close reason.
+func WebsocketErrorHandler(ctx context.Context, err error) {
+	_, v := describe(err)
 
-	switch e := errors.Cause(err).(type) {
-	case InvalidError:
-		v := JSONError{
-			Error:  ErrValidation.Error(),
-			Fields: e,
-		}
+	if _, ok := errors.Cause(err).(InvalidError); ok {
 		websocketRespond(ctx, v)
 		return
-	case ResponseError:
-		websocketRespondError(ctx, e.Err, websocket.CloseInternalServerErr)
-		return
 	}
 
-	websocketRespondError(ctx, err, websocket.CloseInternalServerErr)
+	websocketRespondError(ctx, v, websocket.CloseInternalServerErr)
 }
 
 func errorHandler(ctx context.Context, w http.ResponseWriter, err error) {
@@ -221,16 +411,21 @@ func isWebsocket(ctx context.Context) bool {
 
 func websocketRespond(ctx context.Context, data interface{}) {
 	if err := WebsocketRespond(ctx, data); err != nil {
-		logStdErr.Println(err)
+		logger.ErrorContext(ctx, "websocket respond", "error", err)
 	}
 }
 
-func WebsocketRespond(ctx context.Context, data interface{}) error {
+func WebsocketRespond(ctx context.Context, data interface{}) (err error) {
 	wsConn, ok := ctx.Value(WebsocketConnection).(*websocket.Conn)
 	if !ok {
 		return ErrCtxNoWebsocketConnection
 	}
 
+	topic, _ := ctx.Value(WebsocketTopic).(string)
+	_, span := tracing.Start(ctx, "websocket.push", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("websocket.topic", topic)))
+	defer func() { tracing.End(span, err) }()
+
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return errors.Wrap(err, "")
@@ -241,12 +436,14 @@ func WebsocketRespond(ctx context.Context, data interface{}) error {
 		return errors.Wrap(err, "")
 	}
 
+	metrics.WebsocketMessage(topic)
+
 	return nil
 }
 
 func websocketRespondError(ctx context.Context, data interface{}, code int) {
 	if err := WebsocketRespondError(ctx, data, code); err != nil {
-		logStdErr.Println(err)
+		logger.ErrorContext(ctx, "websocket respond error", "error", err)
 	}
 }
 
@@ -9,12 +9,20 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/gorilla/mux"
 
 	"github.com/pborman/uuid"
+	"github.com/timurguseynov/go-wallet-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TraceIDHeader is the header added to outgoing requests which adds the
@@ -70,13 +78,37 @@ const (
 	// KeyValues is how request values or stored/retrieved.
 	KeyValues ctxKey = iota
 	WebsocketConnection
+	WebsocketTopic
 )
 
-// Values represent state for each request.
+// Auth methods a request can be authenticated with.
+const (
+	AuthNone  = "none"
+	AuthHMAC  = "hmac"
+	AuthToken = "token"
+)
+
+// Values represent state for each request. Actor and AuthMethod are set by
+// the middleware that authenticated the request. UserID is the user the
+// request is about, taken from the userID route parameter or set by the
+// handler. Problem is set when the request accepts errors as problem
+// details.
 type Values struct {
 	TraceID    string
 	Now        time.Time
 	StatusCode int
+	Route      string
+	UserID     string
+	Actor      string
+	AuthMethod string
+	Problem    bool
+}
+
+// SetUserID records the user a request is about when it isn't in the route.
+func SetUserID(ctx context.Context, userID string) {
+	if v, ok := ctx.Value(KeyValues).(*Values); ok {
+		v.UserID = userID
+	}
 }
 
 // A Handler is a type that handles an http request within our own little mini
@@ -92,7 +124,16 @@ type Middleware func(Handler) Handler
 // data/logic on this App struct
 type App struct {
 	*mux.Router
-	mw []Middleware
+	mw     []Middleware
+	routes []route
+	docs   map[string]Doc
+}
+
+// route is a route registered through Handle. aliasOf is the key of the
+// route it's a deprecated alias of, if it's one.
+type route struct {
+	key     string
+	aliasOf string
 }
 
 // New creates an App value that handle a set of routes for the application.
@@ -119,33 +160,56 @@ func (a *App) Handle(verb, path string, handler Handler, mw ...Middleware) {
 	// Wrap up the application-wide first, this will call the first function
 	// of each middleware which will return a function of type Handler. Each
 	// Handler will then be wrapped up with the other handlers from the chain.
-	handler = wrapMiddleware(wrapMiddleware(handler, mw), a.mw)
+	// Every layer gets a span of its own.
+	handler = wrapMiddleware(wrapMiddleware(traced(handler), mw), a.mw)
 
 	// The function to execute for each request.
 	h := func(w http.ResponseWriter, r *http.Request) {
 
+		// Extract url params like /product/:id to use as a map in handler
+		vars := mux.Vars(r)
+
+		// Continue the trace of the caller, if it sent one.
+		ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), verb+" "+path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", verb),
+				attribute.String("http.route", path),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
 		// Set the context with the required values to
-		// process the request.
+		// process the request. Callers that send their own trace ID keep
+		// it, the others get the ID of the trace.
 		v := Values{
-			TraceID: uuid.New(),
+			TraceID: traceID(ctx, r),
 			Now:     time.Now(),
+			Route:   path,
+			UserID:  vars["userID"],
+			Problem: acceptsProblem(r),
 		}
-		ctx := context.WithValue(r.Context(), KeyValues, &v)
+		ctx = context.WithValue(ctx, KeyValues, &v)
+		span.SetAttributes(attribute.String("wallet.trace_id", v.TraceID))
 
 		// Set the trace id on the outgoing requests before any other header to
 		// ensure that the trace id is ALWAYS added to the request regardless of
 		// any error occuring or not.
 		w.Header().Set(TraceIDHeader, v.TraceID)
 
-		// Extract url params like /product/:id to use as a map in handler
-		vars := mux.Vars(r)
-
 		// Call the wrapped handler functions.
 		handler(ctx, w, r, vars)
+
+		span.SetAttributes(attribute.Int("http.response.status_code", v.StatusCode))
+		if v.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(v.StatusCode))
+		}
 	}
 
 	// Add this handler for the specified verb and route.
 	a.Router.HandleFunc(path, h).Methods(verb)
+	a.routes = append(a.routes, route{key: routeKey(verb, path)})
 }
 
 // wrapMiddleware wraps a handler with some middleware.
@@ -153,13 +217,138 @@ func wrapMiddleware(handler Handler, mw []Middleware) Handler {
 	// Wrap with our group specific middleware.
 	for i := len(mw) - 1; i >= 0; i-- {
 		if mw[i] != nil {
-			handler = mw[i](handler)
+			handler = traced(mw[i](handler))
 		}
 	}
 
 	return handler
 }
 
+// traceID returns the trace ID of the request, the one the caller sent in
+// TraceIDHeader if any.
+func traceID(ctx context.Context, r *http.Request) string {
+	if id := r.Header.Get(TraceIDHeader); id != "" {
+		return id
+	}
+	if id := tracing.TraceID(ctx); id != "" {
+		return id
+	}
+	return uuid.New()
+}
+
+// traced wraps handler in a span named after the function it is.
+func traced(handler Handler) Handler {
+	name := funcName(handler)
+
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		ctx, span := tracing.Start(ctx, name)
+		err := handler(ctx, w, r, params)
+		tracing.End(span, err)
+		return err
+	}
+}
+
+// funcName returns the package qualified name of the function f, closures
+// named after the function that returned them.
+func funcName(f interface{}) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "handler"
+	}
+
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	for {
+		i := strings.LastIndex(name, ".")
+		last := strings.TrimPrefix(name[i+1:], "func")
+		if _, err := strconv.Atoi(last); i < 0 || err != nil {
+			break
+		}
+		name = name[:i]
+	}
+
+	return name
+}
+
+// Group is a set of routes sharing a path prefix and middleware of their own,
+// which run inside the App's middleware. The routes can be served under
+// deprecated prefixes too, see Alias.
+type Group struct {
+	app     *App
+	prefix  string
+	mw      []Middleware
+	aliases []alias
+}
+
+// alias is a prefix a group's routes are still served under until they're
+// retired.
+type alias struct {
+	prefix      string
+	deprecation Deprecation
+}
+
+// Deprecation says when routes stopped being the ones to use and when they
+// go away. Sunset is left out of the responses when it's zero.
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time
+}
+
+// Group creates a Group of routes mounted under prefix.
+func (a *App) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		app:    a,
+		prefix: prefix,
+		mw:     mw,
+	}
+}
+
+// Group creates a Group mounted under prefix inside g. Its middleware runs
+// inside g's and its routes are served under g's aliases too.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	chain := make([]Middleware, 0, len(g.mw)+len(mw))
+	chain = append(chain, g.mw...)
+	chain = append(chain, mw...)
+
+	sub := Group{
+		app:    g.app,
+		prefix: g.prefix + prefix,
+		mw:     chain,
+	}
+	for _, al := range g.aliases {
+		sub.aliases = append(sub.aliases, alias{prefix: al.prefix + prefix, deprecation: al.deprecation})
+	}
+
+	return &sub
+}
+
+// Alias serves the routes of the group, those mounted after the call
+// included, under prefix as well. Responses on those paths say they're
+// deprecated and link to the same path under the group's prefix.
+func (g *Group) Alias(prefix string, d Deprecation) *Group {
+	g.aliases = append(g.aliases, alias{prefix: prefix, deprecation: d})
+	return g
+}
+
+// Handle mounts the handler for the verb and path relative to the group's
+// prefix, and to the prefixes of its aliases.
+func (g *Group) Handle(verb, path string, handler Handler, mw ...Middleware) {
+	chain := make([]Middleware, 0, len(g.mw)+len(mw))
+	chain = append(chain, g.mw...)
+	chain = append(chain, mw...)
+
+	g.app.Handle(verb, g.prefix+path, handler, chain...)
+
+	for _, al := range g.aliases {
+		dchain := append([]Middleware{deprecationMiddleware(al.deprecation, al.prefix, g.prefix)}, chain...)
+		g.app.Handle(verb, al.prefix+path, handler, dchain...)
+
+		// The alias is documented by the route it's an alias of.
+		g.app.routes[len(g.app.routes)-1].aliasOf = routeKey(verb, g.prefix+path)
+	}
+}
+
 func (a *App) WebsocketHandle(path string, handler Handler, mw ...Middleware) {
 	a.Handle(http.MethodGet, path, websocketMiddleware(handler), mw...)
 }
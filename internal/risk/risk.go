@@ -0,0 +1,315 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/rest"
+	"gopkg.in/yaml.v3"
+)
+
+// Decisions a rule can make. Deny wins over review and review over allow.
+const (
+	Allow  = "allow"
+	Review = "review"
+	Deny   = "deny"
+)
+
+// Rule types.
+const (
+	// TypeAmount matches when the amount is at least Rule.Amount.
+	TypeAmount = "amount"
+	// TypeNewAccount matches when the account is younger than Rule.Age.
+	TypeNewAccount = "new_account"
+	// TypeVelocity matches when the user already made Rule.Count
+	// transactions within Rule.Window.
+	TypeVelocity = "velocity"
+	// TypeBlocked matches when any side of the transaction is in Rule.Users.
+	TypeBlocked = "blocked"
+)
+
+var (
+	ErrDenied      = errors.New("transaction denied")
+	ErrInvalidRule = errors.New("invalid rule")
+)
+
+// Duration is a time.Duration that reads from JSON strings like "24h".
+type Duration time.Duration
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Rule is a single check evaluated before a transaction is committed. Ops
+// limits the rule to some transaction types, empty means all of them.
+type Rule struct {
+	ID       string   `json:"id"`
+	Type     string   `json:"type"`
+	Ops      []string `json:"ops,omitempty"`
+	Amount   int64    `json:"amount,omitempty"`
+	Age      Duration `json:"age,omitempty"`
+	Window   Duration `json:"window,omitempty"`
+	Count    int64    `json:"count,omitempty"`
+	Users    []string `json:"users,omitempty"`
+	Decision string   `json:"decision"`
+}
+
+// Request describes the transaction being evaluated.
+type Request struct {
+	Op               string
+	UserID           string
+	CounterpartyID   string
+	Amount           int64
+	AccountCreatedAt time.Time
+	Now              time.Time
+}
+
+// Result is the outcome of the evaluation and the rule that decided it.
+type Result struct {
+	Decision string `json:"decision"`
+	RuleID   string `json:"rule_id,omitempty"`
+}
+
+// Load replaces the rules with the ones from the file at path, YAML when it
+// ends in .yaml or .yml and JSON otherwise.
+func Load(ctx context.Context, dbConn *db.DB, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if b, err = yamlToJSON(b); err != nil {
+			return err
+		}
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	ids := map[string]bool{}
+	for _, r := range rules {
+		if err := r.validate(); err != nil {
+			return err
+		}
+		if ids[r.ID] {
+			return errors.Wrapf(ErrInvalidRule, "%s: duplicate id", r.ID)
+		}
+		ids[r.ID] = true
+	}
+
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	if _, err := txn.DeleteAll("rule", "id"); err != nil {
+		return errors.Wrap(err, "txn.DeleteAll")
+	}
+
+	for _, r := range rules {
+		if err := txn.Insert("rule", r); err != nil {
+			return errors.Wrap(err, "txn.Insert")
+		}
+	}
+
+	txn.Commit()
+
+	return nil
+}
+
+// yamlToJSON converts a YAML document to JSON, so rules read from both
+// formats go through the same field names and Duration parsing.
+func yamlToJSON(b []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, errors.Wrap(err, "yaml.Unmarshal")
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "json.Marshal")
+	}
+
+	return b, nil
+}
+
+// Watch reloads the rules whenever the file at path changes, until ctx is
+// done. A file that fails to load leaves the previous rules in place.
+func Watch(ctx context.Context, dbConn *db.DB, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var modTime time.Time
+	if fi, err := os.Stat(path); err == nil {
+		modTime = fi.ModTime()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fi, err := os.Stat(path)
+			if err != nil || !fi.ModTime().After(modTime) {
+				continue
+			}
+			modTime = fi.ModTime()
+
+			if err := Load(ctx, dbConn, path); err != nil {
+				rest.Logger().ErrorContext(ctx, "couldn't reload risk rules", "path", path, "error", err)
+				continue
+			}
+			rest.Logger().InfoContext(ctx, "reloaded risk rules", "path", path)
+		}
+	}
+}
+
+// Evaluate runs every rule against req. It returns ErrDenied together with
+// the result if any rule denies the transaction.
+func Evaluate(txn *memdb.Txn, req Request) (Result, error) {
+	res := Result{Decision: Allow}
+
+	it, err := txn.Get("rule", "id")
+	if err != nil {
+		return res, errors.Wrap(err, "txn.Get")
+	}
+
+	var ts []ledger.Transaction
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		r, ok := obj.(Rule)
+		if !ok {
+			return res, errors.New("couldn't type assert rule")
+		}
+
+		if !r.appliesTo(req.Op) {
+			continue
+		}
+
+		var match bool
+		switch r.Type {
+		case TypeAmount:
+			match = req.Amount >= r.Amount
+		case TypeNewAccount:
+			match = req.Now.Sub(req.AccountCreatedAt) < time.Duration(r.Age)
+		case TypeVelocity:
+			if ts == nil {
+				ts, err = ledger.ListByAccount(txn, req.UserID)
+				if err != nil {
+					return res, errors.Wrap(err, "")
+				}
+			}
+			since := req.Now.Add(-time.Duration(r.Window))
+			var count int64
+			for _, t := range ts {
+				if t.UserID == req.UserID && !t.CreatedAt.Before(since) {
+					count++
+				}
+			}
+			match = count >= r.Count
+		case TypeBlocked:
+			for _, id := range r.Users {
+				if id == req.UserID || id == req.CounterpartyID {
+					match = true
+					break
+				}
+			}
+		}
+
+		if match && severity[r.Decision] > severity[res.Decision] {
+			res = Result{Decision: r.Decision, RuleID: r.ID}
+		}
+	}
+
+	if res.Decision == Deny {
+		return res, errors.Wrapf(ErrDenied, "rule %s", res.RuleID)
+	}
+
+	return res, nil
+}
+
+var severity = map[string]int{
+	Allow:  0,
+	Review: 1,
+	Deny:   2,
+}
+
+func (r Rule) appliesTo(op string) bool {
+	if len(r.Ops) == 0 {
+		return true
+	}
+	for _, o := range r.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) validate() error {
+	if r.ID == "" {
+		return errors.Wrap(ErrInvalidRule, "missing id")
+	}
+
+	switch r.Type {
+	case TypeAmount, TypeNewAccount, TypeVelocity, TypeBlocked:
+	default:
+		return errors.Wrapf(ErrInvalidRule, "%s: unknown type %q", r.ID, r.Type)
+	}
+
+	if _, ok := severity[r.Decision]; !ok {
+		return errors.Wrapf(ErrInvalidRule, "%s: unknown decision %q", r.ID, r.Decision)
+	}
+
+	for _, op := range r.Ops {
+		if !ops[op] {
+			return errors.Wrapf(ErrInvalidRule, "%s: unknown op %q", r.ID, op)
+		}
+	}
+
+	switch {
+	case r.Type == TypeAmount && r.Amount <= 0:
+		return errors.Wrapf(ErrInvalidRule, "%s: amount must be positive", r.ID)
+	case r.Type == TypeNewAccount && r.Age <= 0:
+		return errors.Wrapf(ErrInvalidRule, "%s: age must be positive", r.ID)
+	case r.Type == TypeVelocity && r.Count <= 0:
+		return errors.Wrapf(ErrInvalidRule, "%s: count must be positive", r.ID)
+	case r.Type == TypeVelocity && r.Window <= 0:
+		return errors.Wrapf(ErrInvalidRule, "%s: window must be positive", r.ID)
+	}
+
+	return nil
+}
+
+// ops are the transaction types rules are evaluated for.
+var ops = map[string]bool{
+	ledger.TypeDeposit:  true,
+	ledger.TypeWithdraw: true,
+	ledger.TypeTransfer: true,
+}
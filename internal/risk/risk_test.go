@@ -0,0 +1,156 @@
+package risk_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/risk"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var (
+	ctx       context.Context
+	rulesPath string
+	userID    string
+	blockedID string
+)
+
+func TestRisk(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	var err error
+	userID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	blockedID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Mallory"})
+	assert.NoError(t, err)
+
+	rulesPath = filepath.Join(t.TempDir(), "rules.json")
+
+	t.Run("riskLoad", riskLoad)
+	t.Run("riskLoadInvalid", riskLoadInvalid)
+	t.Run("riskLoadYAML", riskLoadYAML)
+	t.Run("riskDenyAmount", riskDenyAmount)
+	t.Run("riskReviewRecorded", riskReviewRecorded)
+	t.Run("riskDenyBlocked", riskDenyBlocked)
+}
+
+func riskLoad(t *testing.T) {
+	rules := `[
+		{"id": "big-deposit", "type": "amount", "ops": ["deposit"], "amount": 10000, "decision": "deny"},
+		{"id": "new-account", "type": "new_account", "age": "24h", "decision": "review"},
+		{"id": "blocked", "type": "blocked", "users": ["` + blockedID + `"], "decision": "deny"}
+	]`
+	err := os.WriteFile(rulesPath, []byte(rules), 0600)
+	assert.NoError(t, err)
+
+	err = risk.Load(ctx, test.MasterDB, rulesPath)
+	assert.NoError(t, err)
+}
+
+func riskLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid.json")
+	err := os.WriteFile(path, []byte(`[{"id": "x", "type": "amount", "decision": "maybe"}]`), 0600)
+	assert.NoError(t, err)
+
+	err = risk.Load(ctx, test.MasterDB, path)
+	assert.Equal(t, risk.ErrInvalidRule, errors.Cause(err))
+
+	for _, rules := range []string{
+		`[{"id": "x", "type": "amount", "amount": 0, "decision": "deny"}]`,
+		`[{"id": "x", "type": "velocity", "window": "1h", "count": -1, "decision": "deny"}]`,
+		`[{"id": "x", "type": "amount", "ops": ["deposit", "bet"], "amount": 100, "decision": "deny"}]`,
+		`[{"id": "x", "type": "amount", "amount": 100, "decision": "deny"}, {"id": "x", "type": "amount", "amount": 200, "decision": "review"}]`,
+	} {
+		err = os.WriteFile(path, []byte(rules), 0600)
+		assert.NoError(t, err)
+
+		err = risk.Load(ctx, test.MasterDB, path)
+		assert.Equal(t, risk.ErrInvalidRule, errors.Cause(err), rules)
+	}
+
+	// the rules loaded before should still be in place
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, 10000)
+	assert.Equal(t, risk.ErrDenied, errors.Cause(err))
+}
+
+func riskLoadYAML(t *testing.T) {
+	rules := `
+- id: big-deposit
+  type: amount
+  ops: [deposit]
+  amount: 10000
+  decision: deny
+- id: new-account
+  type: new_account
+  age: 24h
+  decision: review
+- id: blocked
+  type: blocked
+  users: ["` + blockedID + `"]
+  decision: deny
+`
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	err := os.WriteFile(path, []byte(rules), 0600)
+	assert.NoError(t, err)
+
+	err = risk.Load(ctx, test.MasterDB, path)
+	assert.NoError(t, err)
+
+	// YAML goes through the same validation.
+	err = os.WriteFile(path, []byte("- {id: x, type: amount, decision: maybe}\n"), 0600)
+	assert.NoError(t, err)
+
+	err = risk.Load(ctx, test.MasterDB, path)
+	assert.Equal(t, risk.ErrInvalidRule, errors.Cause(err))
+}
+
+func riskDenyAmount(t *testing.T) {
+	_, err := user.DepositByID(ctx, test.MasterDB, userID, 10000)
+	assert.Equal(t, risk.ErrDenied, errors.Cause(err))
+	assert.Contains(t, err.Error(), "big-deposit")
+
+	balance, err := user.GetBalanceByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), balance)
+}
+
+func riskReviewRecorded(t *testing.T) {
+	dep, err := user.DepositByID(ctx, test.MasterDB, userID, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, risk.Review, dep.Risk)
+
+	txn := test.MasterDB.Txn(tests.Context(), false)
+	defer txn.Abort()
+
+	ts, err := ledger.ListByAccount(txn, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(ts))
+	assert.Equal(t, risk.Review, ts[0].Risk)
+	assert.Equal(t, "new-account", ts[0].RiskRuleID)
+}
+
+func riskDenyBlocked(t *testing.T) {
+	_, err := user.TransferByID(ctx, test.MasterDB, userID, blockedID, 100)
+	assert.Equal(t, risk.ErrDenied, errors.Cause(err))
+	assert.Contains(t, err.Error(), "blocked")
+}
@@ -0,0 +1,180 @@
+package statement
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+// Line is a movement on the statement. Amount is signed and includes Fee,
+// the part of a debit charged as a fee. Balance is the running balance
+// after the movement.
+type Line struct {
+	Date          time.Time `json:"date"`
+	TransactionID string    `json:"transaction_id"`
+	Type          string    `json:"type"`
+	Amount        int64     `json:"amount"`
+	Fee           int64     `json:"fee"`
+	Balance       int64     `json:"balance"`
+}
+
+// Statement is the cash account of a user over [From, To).
+type Statement struct {
+	UserID      string    `json:"user_id"`
+	Currency    string    `json:"currency"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Opening     int64     `json:"opening"`
+	Closing     int64     `json:"closing"`
+	Fees        int64     `json:"fees"`
+	Lines       []Line    `json:"lines"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Month returns the period covering the month t falls in, in t's location.
+func Month(t time.Time) (from, to time.Time) {
+	from = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return from, from.AddDate(0, 1, 0)
+}
+
+// Generate returns the statement of the user for [from, to), ErrNotFound
+// from the user package if the user doesn't exist.
+func Generate(ctx context.Context, dbConn *db.DB, userID string, from, to time.Time) (*Statement, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	raw, err := txn.First("user", "id", userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.First")
+	}
+	u, ok := raw.(user.User)
+	if !ok || u.DeletedAt != nil {
+		return nil, user.ErrNotFound
+	}
+
+	s, err := generate(txn, u, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// GenerateAll returns the statements of every user for [from, to), all
+// from the same snapshot of the database. It's meant for batch jobs like
+// pre-rendering the month-end statements.
+func GenerateAll(ctx context.Context, dbConn *db.DB, from, to time.Time) ([]Statement, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	it, err := txn.Get("user", "id")
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.Get")
+	}
+
+	var ss []Statement
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		u, ok := obj.(user.User)
+		if !ok {
+			return nil, errors.New("couldn't type assert user")
+		}
+		if u.DeletedAt != nil {
+			continue
+		}
+
+		s, err := generate(txn, u, from, to)
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, s)
+	}
+
+	return ss, nil
+}
+
+func generate(txn *memdb.Txn, u user.User, from, to time.Time) (Statement, error) {
+	r, err := ledger.RangeOf(txn, u.ID, from, to)
+	if err != nil {
+		return Statement{}, errors.Wrap(err, "ledger.RangeOf")
+	}
+
+	s := Statement{
+		UserID:      u.ID,
+		Currency:    u.Currency,
+		From:        from,
+		To:          to,
+		Opening:     r.Opening,
+		Closing:     r.Closing,
+		Lines:       []Line{},
+		GeneratedAt: time.Now(),
+	}
+
+	for _, e := range r.Movements {
+		t, err := ledger.GetByID(txn, e.TransactionID)
+		if err != nil {
+			return Statement{}, errors.Wrap(err, "ledger.GetByID")
+		}
+
+		// The fee is charged to the side that's debited.
+		var fee int64
+		if e.Amount < 0 {
+			fee = t.Fee
+		}
+
+		s.Fees += fee
+		s.Lines = append(s.Lines, Line{
+			Date:          e.CreatedAt,
+			TransactionID: e.TransactionID,
+			Type:          e.Type,
+			Amount:        e.Amount,
+			Fee:           fee,
+			Balance:       e.Balance,
+		})
+	}
+
+	return s, nil
+}
+
+// WriteCSV writes the statement as CSV, one row per line between a row with
+// the opening balance and one with the fees and the closing balance.
+func WriteCSV(w io.Writer, s Statement) error {
+	cw := csv.NewWriter(w)
+
+	rows := [][]string{
+		{"date", "transaction_id", "type", "amount", "fee", "balance"},
+		{formatTime(s.From), "", "opening_balance", "", "", formatInt(s.Opening)},
+	}
+	for _, l := range s.Lines {
+		rows = append(rows, []string{
+			formatTime(l.Date),
+			l.TransactionID,
+			l.Type,
+			formatInt(l.Amount),
+			formatInt(l.Fee),
+			formatInt(l.Balance),
+		})
+	}
+	rows = append(rows, []string{formatTime(s.To), "", "closing_balance", "", formatInt(s.Fees), formatInt(s.Closing)})
+
+	if err := cw.WriteAll(rows); err != nil {
+		return errors.Wrap(err, "csv.WriteAll")
+	}
+
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
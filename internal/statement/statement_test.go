@@ -0,0 +1,111 @@
+package statement_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/fee"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/statement"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var test *tests.Test
+
+// TestMain is the entry point for testing.
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	test = tests.New()
+	defer test.TearDown()
+	return m.Run()
+}
+
+var (
+	ctx    context.Context
+	userID string
+)
+
+func TestStatement(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	err := fee.Set(ctx, test.MasterDB, fee.Schedule{Op: ledger.TypeWithdraw, Fixed: 10})
+	assert.NoError(t, err)
+
+	userID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, 1000)
+	assert.NoError(t, err)
+	_, err = user.WithdrawByID(ctx, test.MasterDB, userID, 200)
+	assert.NoError(t, err)
+
+	t.Run("statementGenerate", statementGenerate)
+	t.Run("statementEmptyPeriod", statementEmptyPeriod)
+	t.Run("statementCSV", statementCSV)
+	t.Run("statementGenerateAll", statementGenerateAll)
+}
+
+func statementGenerate(t *testing.T) {
+	from, to := statement.Month(time.Now())
+	s, err := statement.Generate(ctx, test.MasterDB, userID, from, to)
+	assert.NoError(t, err)
+
+	assert.Equal(t, user.DefaultCurrency, s.Currency)
+	assert.Equal(t, int64(0), s.Opening)
+	assert.Equal(t, int64(790), s.Closing)
+	assert.Equal(t, int64(10), s.Fees)
+	if assert.Len(t, s.Lines, 2) {
+		assert.Equal(t, ledger.TypeDeposit, s.Lines[0].Type)
+		assert.Equal(t, int64(1000), s.Lines[0].Balance)
+		assert.Equal(t, int64(-210), s.Lines[1].Amount)
+		assert.Equal(t, int64(10), s.Lines[1].Fee)
+		assert.Equal(t, int64(790), s.Lines[1].Balance)
+	}
+}
+
+func statementEmptyPeriod(t *testing.T) {
+	from, to := statement.Month(time.Now().AddDate(0, 1, 0))
+	s, err := statement.Generate(ctx, test.MasterDB, userID, from, to)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(790), s.Opening, "next month opens with this month's closing")
+	assert.Equal(t, int64(790), s.Closing)
+	assert.Empty(t, s.Lines)
+}
+
+func statementCSV(t *testing.T) {
+	from, to := statement.Month(time.Now())
+	s, err := statement.Generate(ctx, test.MasterDB, userID, from, to)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = statement.WriteCSV(&buf, *s)
+	assert.NoError(t, err)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 5) {
+		assert.Equal(t, "opening_balance", rows[1][2])
+		assert.Equal(t, "-210", rows[3][3])
+		assert.Equal(t, []string{"closing_balance", "", "10", "790"}, rows[4][2:])
+	}
+}
+
+func statementGenerateAll(t *testing.T) {
+	from, to := statement.Month(time.Now())
+	ss, err := statement.GenerateAll(ctx, test.MasterDB, from, to)
+	assert.NoError(t, err)
+
+	users, err := user.List(ctx, test.MasterDB)
+	assert.NoError(t, err)
+	assert.Len(t, ss, len(users))
+}
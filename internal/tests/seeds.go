@@ -13,7 +13,7 @@ import (
 func mustSeed(ctx context.Context, dbConn *db.DB) {
 	err := seed10Users(ctx, dbConn)
 	if err != nil {
-		log.Fatal("couldn't seed users")
+		log.Fatalf("couldn't seed users : %v", err)
 	}
 }
 
@@ -26,7 +26,7 @@ func SeedUser(ctx context.Context, dbConn *db.DB, name string, depositAmount int
 		return errors.Wrap(err, "")
 	}
 
-	err = user.DepositByID(ctx, dbConn, id, int64(depositAmount))
+	_, err = user.DepositByID(ctx, dbConn, id, int64(depositAmount))
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
@@ -36,7 +36,9 @@ func SeedUser(ctx context.Context, dbConn *db.DB, name string, depositAmount int
 
 func seed10Users(ctx context.Context, dbConn *db.DB) error {
 	for i := 0; i < 10; i++ {
-		SeedUser(ctx, dbConn, "Alex", i*100)
+		if err := SeedUser(ctx, dbConn, "Alex", i*100); err != nil {
+			return err
+		}
 	}
 
 	return nil
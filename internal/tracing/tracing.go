@@ -0,0 +1,152 @@
+// Package tracing sets up the OpenTelemetry tracer of the service. Spans are
+// exported over OTLP, written to a file or, by default, not kept at all; the
+// W3C trace context of incoming requests is honored either way.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters spans can be sent to.
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+// ServiceName is the service spans are reported for.
+const ServiceName = "go-wallet-api"
+
+// instrumentation names the tracer of the service's own spans.
+const instrumentation = "github.com/timurguseynov/go-wallet-api"
+
+var (
+	ErrInvalidExporter = errors.New("trace exporter must be none, otlp or file")
+	ErrNoFile          = errors.New("trace file is required by the file exporter")
+)
+
+// propagator reads and writes the traceparent, tracestate and baggage
+// headers.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Options configure Setup. Endpoint is the OTLP/HTTP collector, host and
+// port, the OTEL_EXPORTER_OTLP_* variables are used when it's empty. File is
+// where the file exporter writes spans, one JSON object per line.
+type Options struct {
+	Exporter string
+	Endpoint string
+	Insecure bool
+	File     string
+}
+
+// Setup installs the tracer provider exporting to opts.Exporter. The
+// returned function flushes the spans not exported yet and stops exporting.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	var (
+		sp      sdktrace.SpanProcessor
+		closeFn = func() error { return nil }
+	)
+	switch strings.ToLower(opts.Exporter) {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+
+	case ExporterOTLP:
+		var eopts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			eopts = append(eopts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			eopts = append(eopts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, eopts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "otlptracehttp.New")
+		}
+		sp = sdktrace.NewBatchSpanProcessor(exp)
+
+	case ExporterFile:
+		if opts.File == "" {
+			return nil, ErrNoFile
+		}
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, errors.Wrap(err, "stdouttrace.New")
+		}
+		// Spans are written as they end so the file is complete whenever
+		// it's read.
+		sp = sdktrace.NewSimpleSpanProcessor(exp)
+		closeFn = f.Close
+
+	default:
+		return nil, ErrInvalidExporter
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", ServiceName)))
+	if err != nil {
+		return nil, errors.Wrap(err, "resource.Merge")
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(sp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		if err := tp.Shutdown(ctx); err != nil {
+			return errors.Wrap(err, "tp.Shutdown")
+		}
+		return errors.Wrap(closeFn(), "")
+	}, nil
+}
+
+// Start starts a span of the service as a child of the one in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+// End ends span, marking it failed when err isn't nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Extract returns ctx with the remote span of the trace context in header,
+// if there's a valid one.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// TraceID returns the ID of the trace the span in ctx is part of, empty when
+// it's in none.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
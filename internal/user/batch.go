@@ -0,0 +1,81 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/metrics"
+)
+
+// MaxBatch is the most operations a batch can have.
+const MaxBatch = 10000
+
+var ErrInvalidOperation = errors.New("operation must be deposit, withdraw or transfer")
+
+// Operation is a deposit, withdraw or transfer in a batch. To is only used
+// by transfers.
+type Operation struct {
+	Op     string
+	UserID string
+	To     string
+	Amount int64
+}
+
+// BatchError is the error of the operation at Index that made a batch fail.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+// Error implements the error interface for BatchError.
+func (e BatchError) Error() string {
+	return fmt.Sprintf("operation %d : %v", e.Index, e.Err)
+}
+
+// ApplyBatch applies the operations in order in a single transaction, all
+// of them or none. Each operation sees the balances and limits left by the
+// ones before it. When one fails nothing is committed and the error is a
+// BatchError.
+func ApplyBatch(ctx context.Context, dbConn *db.DB, ops []Operation) ([]ledger.Transaction, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	now := time.Now()
+
+	ts := make([]ledger.Transaction, 0, len(ops))
+	for i, op := range ops {
+		var (
+			t   ledger.Transaction
+			err error
+		)
+
+		switch op.Op {
+		case ledger.TypeDeposit:
+			t, err = deposit(txn, op.UserID, op.Amount, now)
+		case ledger.TypeWithdraw:
+			t, err = withdraw(txn, op.UserID, op.Amount, now)
+		case ledger.TypeTransfer:
+			t, err = transfer(txn, op.UserID, op.To, op.Amount, now)
+		default:
+			err = ErrInvalidOperation
+		}
+		if err != nil {
+			metrics.Operation(op.Op, outcome(err), op.Amount)
+			return nil, BatchError{Index: i, Err: err}
+		}
+
+		ts = append(ts, t)
+	}
+
+	txn.Commit()
+
+	for _, op := range ops {
+		metrics.Operation(op.Op, metrics.OutcomeOK, op.Amount)
+	}
+
+	return ts, nil
+}
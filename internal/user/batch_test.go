@@ -0,0 +1,64 @@
+package user_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+func TestBatch(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	t.Run("batchApply", batchApply)
+	t.Run("batchAllOrNothing", batchAllOrNothing)
+}
+
+func batchUsers(t *testing.T) (string, string) {
+	fromID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	toID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Kim"})
+	assert.NoError(t, err)
+	return fromID, toID
+}
+
+func batchApply(t *testing.T) {
+	fromID, toID := batchUsers(t)
+
+	// later operations see the balances left by earlier ones
+	ts, err := user.ApplyBatch(ctx, test.MasterDB, []user.Operation{
+		{Op: ledger.TypeDeposit, UserID: fromID, Amount: 500},
+		{Op: ledger.TypeTransfer, UserID: fromID, To: toID, Amount: 300},
+		{Op: ledger.TypeWithdraw, UserID: toID, Amount: 100},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, ts, 3) {
+		assert.Equal(t, ledger.TypeTransfer, ts[1].Type)
+		assert.Equal(t, ts[0].Seq+1, ts[1].Seq)
+	}
+
+	balance, err := user.GetBalanceByID(ctx, test.MasterDB, fromID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), balance)
+	balance, err = user.GetBalanceByID(ctx, test.MasterDB, toID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), balance)
+}
+
+func batchAllOrNothing(t *testing.T) {
+	fromID, toID := batchUsers(t)
+
+	_, err := user.ApplyBatch(ctx, test.MasterDB, []user.Operation{
+		{Op: ledger.TypeDeposit, UserID: fromID, Amount: 500},
+		{Op: ledger.TypeTransfer, UserID: fromID, To: toID, Amount: 600},
+		{Op: ledger.TypeDeposit, UserID: toID, Amount: 100},
+	})
+	assert.Equal(t, user.BatchError{Index: 1, Err: user.ErrInsufficientFunds}, err)
+
+	balance, err := user.GetBalanceByID(ctx, test.MasterDB, fromID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), balance, "the deposit before the failure should be rolled back")
+}
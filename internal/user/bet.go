@@ -0,0 +1,356 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+)
+
+// betHoldTTL keeps the stake held until the bet is settled, however long the
+// market takes.
+const betHoldTTL = 365 * 24 * time.Hour
+
+// Bet statuses. Open bets hold the stake, the rest are settlement outcomes.
+// Void and push both return the stake.
+const (
+	BetOpen = "open"
+	BetWin  = "win"
+	BetLose = "lose"
+	BetVoid = "void"
+	BetPush = "push"
+)
+
+var (
+	ErrBetNotFound      = errors.New("bet not found")
+	ErrBetSettled       = errors.New("bet is already settled")
+	ErrInvalidOutcome   = errors.New("invalid outcome")
+	ErrMissingSelection = errors.New("no outcome for selection")
+)
+
+// Bet is a stake on a selection of a market. Odds are decimal odds in
+// hundredths, 250 pays 2.50 times the stake on a win. BonusStake is the part
+// of the stake paid with funds of bonus BonusID, the rest is held from cash.
+// Payout is what the user got back on settlement. SettleSeq orders
+// settlements for the outcomes stream and is zero while the bet is open.
+type Bet struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Market     string     `json:"market"`
+	Selection  string     `json:"selection"`
+	Stake      int64      `json:"stake"`
+	BonusStake int64      `json:"bonus_stake,omitempty"`
+	BonusID    string     `json:"bonus_id,omitempty"`
+	Odds       int64      `json:"odds"`
+	HoldID     string     `json:"hold_id,omitempty"`
+	Status     string     `json:"status"`
+	Payout     int64      `json:"payout"`
+	PlacedAt   time.Time  `json:"placed_at"`
+	SettledAt  *time.Time `json:"settled_at,omitempty"`
+	SettleSeq  uint64     `json:"settle_seq,omitempty"`
+}
+
+// PlaceBet holds the stake and records the bet as open. The stake comes out
+// of the available cash first and out of the active bonus for the rest.
+func PlaceBet(ctx context.Context, dbConn *db.DB, b Bet) (*Bet, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	now := time.Now()
+
+	user, err := get(txn, b.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := user.canSend(); err != nil {
+		return nil, err
+	}
+
+	cash := b.Stake
+	b.BonusStake, b.BonusID = 0, ""
+	if available := user.Available(); cash > available {
+		if available < 0 {
+			available = 0
+		}
+		b.BonusStake, b.BonusID, err = stakeBonus(txn, b.UserID, cash-available, now)
+		if err != nil {
+			return nil, err
+		}
+		cash = cash - b.BonusStake
+	}
+
+	b.ID = uuid.New().String()
+	b.HoldID = ""
+	if cash > 0 {
+		h, err := createHold(txn, b.UserID, cash, betHoldTTL, b.Market+"/"+b.Selection, b.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		b.HoldID = h.ID
+	}
+
+	b.Status = BetOpen
+	b.Payout = 0
+	b.PlacedAt = now
+	b.SettledAt = nil
+	b.SettleSeq = 0
+
+	if err := txn.Insert("bet", b); err != nil {
+		return nil, errors.Wrap(err, "txn.Insert")
+	}
+
+	txn.Commit()
+
+	return &b, nil
+}
+
+func GetBetByID(ctx context.Context, dbConn *db.DB, betID string) (*Bet, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	b, err := getBet(txn, betID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// SettleBet settles an open bet with outcome. A win captures the stake and
+// pays stake times odds out of the house account, a loss captures the stake,
+// void and push release it.
+func SettleBet(ctx context.Context, dbConn *db.DB, betID string, outcome string) (*Bet, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	b, err := settle(txn, betID, outcome, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	txn.Commit()
+
+	return &b, nil
+}
+
+// SettleMarket settles every open bet of the market with the outcome given
+// for its selection, all or nothing.
+func SettleMarket(ctx context.Context, dbConn *db.DB, market string, outcomes map[string]string) ([]Bet, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	it, err := txn.Get("bet", "market", market)
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.Get")
+	}
+
+	var open []Bet
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		b, ok := obj.(Bet)
+		if !ok {
+			return nil, errors.New("couldn't type assert bet")
+		}
+		if b.Status == BetOpen {
+			open = append(open, b)
+		}
+	}
+
+	now := time.Now()
+	settled := []Bet{}
+	for _, b := range open {
+		outcome, ok := outcomes[b.Selection]
+		if !ok {
+			return nil, errors.Wrap(ErrMissingSelection, b.Selection)
+		}
+
+		b, err := settle(txn, b.ID, outcome, now)
+		if err != nil {
+			return nil, err
+		}
+		settled = append(settled, b)
+	}
+
+	txn.Commit()
+
+	return settled, nil
+}
+
+// ListSettledSince returns the bets settled after the settlement with seq, in
+// the order they were settled.
+func ListSettledSince(ctx context.Context, dbConn *db.DB, seq uint64) ([]Bet, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	it, err := txn.LowerBound("bet", "settle_seq", seq+1)
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.LowerBound")
+	}
+
+	var bets []Bet
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		b, ok := obj.(Bet)
+		if !ok {
+			return nil, errors.New("couldn't type assert bet")
+		}
+		bets = append(bets, b)
+	}
+
+	return bets, nil
+}
+
+// LastSettleSeq returns the seq of the latest settlement, zero if there's
+// none yet.
+func LastSettleSeq(ctx context.Context, dbConn *db.DB) (uint64, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	return lastSettleSeq(txn)
+}
+
+func lastSettleSeq(txn *memdb.Txn) (uint64, error) {
+	raw, err := txn.Last("bet", "settle_seq")
+	if err != nil {
+		return 0, errors.Wrap(err, "txn.Last")
+	}
+	if raw == nil {
+		return 0, nil
+	}
+
+	b, ok := raw.(Bet)
+	if !ok {
+		return 0, errors.New("couldn't type assert bet")
+	}
+
+	return b.SettleSeq, nil
+}
+
+func getBet(txn *memdb.Txn, betID string) (Bet, error) {
+	raw, err := txn.First("bet", "id", betID)
+	if err != nil {
+		return Bet{}, errors.Wrap(err, "txn.First")
+	}
+	if raw == nil {
+		return Bet{}, ErrBetNotFound
+	}
+
+	b, ok := raw.(Bet)
+	if !ok {
+		return Bet{}, errors.New("couldn't type assert bet")
+	}
+
+	return b, nil
+}
+
+func settle(txn *memdb.Txn, betID string, outcome string, now time.Time) (Bet, error) {
+	b, err := getBet(txn, betID)
+	if err != nil {
+		return b, err
+	}
+
+	if b.Status != BetOpen {
+		return b, ErrBetSettled
+	}
+
+	switch outcome {
+	case BetWin:
+		if err := captureStake(txn, b, now); err != nil {
+			return b, err
+		}
+		// Winnings on the bonus funded part of the stake are bonus funds too.
+		b.Payout = b.Stake * b.Odds / 100
+		bonus := b.Payout * b.BonusStake / b.Stake
+		if err := payout(txn, b.UserID, b.Payout-bonus, now); err != nil {
+			return b, err
+		}
+		if err := creditBonus(txn, b.UserID, b.BonusID, bonus, now); err != nil {
+			return b, err
+		}
+		if err := wager(txn, b.UserID, b.Stake, now); err != nil {
+			return b, err
+		}
+	case BetLose:
+		if err := captureStake(txn, b, now); err != nil {
+			return b, err
+		}
+		if err := wager(txn, b.UserID, b.Stake, now); err != nil {
+			return b, err
+		}
+	case BetVoid, BetPush:
+		if b.HoldID != "" {
+			if _, err := releaseHold(txn, b.HoldID, HoldVoided); err != nil {
+				return b, err
+			}
+		}
+		if err := creditBonus(txn, b.UserID, b.BonusID, b.BonusStake, now); err != nil {
+			return b, err
+		}
+		b.Payout = b.Stake
+	default:
+		return b, ErrInvalidOutcome
+	}
+
+	seq, err := lastSettleSeq(txn)
+	if err != nil {
+		return b, err
+	}
+
+	b.Status = outcome
+	b.SettledAt = &now
+	b.SettleSeq = seq + 1
+
+	if err := txn.Insert("bet", b); err != nil {
+		return b, errors.Wrap(err, "txn.Insert")
+	}
+
+	return b, nil
+}
+
+// captureStake takes the cash part of the stake, the bonus part was taken
+// when the bet was placed.
+func captureStake(txn *memdb.Txn, b Bet, now time.Time) error {
+	if b.HoldID == "" {
+		return nil
+	}
+
+	_, err := captureHold(txn, b.HoldID, 0, now)
+	return err
+}
+
+// payout credits the user with amount from the house account.
+func payout(txn *memdb.Txn, userID string, amount int64, now time.Time) error {
+	if amount == 0 {
+		return nil
+	}
+
+	user, err := get(txn, userID)
+	if err != nil {
+		return err
+	}
+
+	user.Balance = user.Balance + amount
+
+	if err := txn.Insert("user", user); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	_, err = ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypePayout,
+		UserID: userID,
+		Amount: amount,
+		Postings: []ledger.Posting{
+			{AccountID: userID, Amount: amount},
+			{AccountID: ledger.House, Amount: -amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return errors.Wrap(err, "ledger.Record")
+	}
+
+	return nil
+}
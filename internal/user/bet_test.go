@@ -0,0 +1,121 @@
+package user_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var betUserID string
+
+func TestBet(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	var err error
+	betUserID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, betUserID, 1000)
+	assert.NoError(t, err)
+
+	t.Run("betPlaceHoldsStake", betPlaceHoldsStake)
+	t.Run("betSettleMarket", betSettleMarket)
+	t.Run("betSettleTwice", betSettleTwice)
+	t.Run("betListSettledSince", betListSettledSince)
+	t.Run("betHoldOwnedByBet", betHoldOwnedByBet)
+}
+
+func placeBet(t *testing.T, market, selection string, stake int64) *user.Bet {
+	b, err := user.PlaceBet(ctx, test.MasterDB, user.Bet{
+		UserID:    betUserID,
+		Market:    market,
+		Selection: selection,
+		Stake:     stake,
+		Odds:      250,
+	})
+	assert.NoError(t, err)
+	return b
+}
+
+func betPlaceHoldsStake(t *testing.T) {
+	b := placeBet(t, "match-1", "home", 400)
+	assert.Equal(t, user.BetOpen, b.Status)
+
+	u, err := user.GetByID(ctx, test.MasterDB, betUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), u.Balance)
+	assert.Equal(t, int64(600), u.Available())
+
+	_, err = user.PlaceBet(ctx, test.MasterDB, user.Bet{UserID: betUserID, Market: "match-1", Selection: "away", Stake: 700, Odds: 200})
+	assert.Equal(t, user.ErrInsufficientFunds, err)
+}
+
+func betSettleMarket(t *testing.T) {
+	placeBet(t, "match-1", "away", 100)
+	placeBet(t, "match-1", "draw", 100)
+
+	_, err := user.SettleMarket(ctx, test.MasterDB, "match-1", map[string]string{"home": user.BetWin})
+	assert.Equal(t, user.ErrMissingSelection, errors.Cause(err))
+
+	bets, err := user.SettleMarket(ctx, test.MasterDB, "match-1", map[string]string{
+		"home": user.BetWin,
+		"away": user.BetLose,
+		"draw": user.BetVoid,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(bets))
+
+	// 1000 - 400 - 100 staked, 400 * 2.50 won, draw stake returned
+	u, err := user.GetByID(ctx, test.MasterDB, betUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), u.Balance)
+	assert.Equal(t, int64(0), u.Held)
+}
+
+func betSettleTwice(t *testing.T) {
+	b := placeBet(t, "match-2", "home", 100)
+
+	_, err := user.SettleBet(ctx, test.MasterDB, b.ID, "maybe")
+	assert.Equal(t, user.ErrInvalidOutcome, err)
+
+	_, err = user.SettleBet(ctx, test.MasterDB, b.ID, user.BetPush)
+	assert.NoError(t, err)
+
+	_, err = user.SettleBet(ctx, test.MasterDB, b.ID, user.BetWin)
+	assert.Equal(t, user.ErrBetSettled, err)
+}
+
+func betListSettledSince(t *testing.T) {
+	last, err := user.LastSettleSeq(ctx, test.MasterDB)
+	assert.NoError(t, err)
+
+	b := placeBet(t, "match-3", "home", 100)
+	_, err = user.SettleBet(ctx, test.MasterDB, b.ID, user.BetLose)
+	assert.NoError(t, err)
+
+	bets, err := user.ListSettledSince(ctx, test.MasterDB, last)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(bets))
+	assert.Equal(t, b.ID, bets[0].ID)
+	assert.Equal(t, last+1, bets[0].SettleSeq)
+}
+
+func betHoldOwnedByBet(t *testing.T) {
+	b := placeBet(t, "match-4", "home", 100)
+
+	h, err := user.GetHoldByID(ctx, test.MasterDB, b.HoldID)
+	assert.NoError(t, err)
+	assert.Equal(t, b.ID, h.BetID)
+
+	_, err = user.VoidHold(ctx, test.MasterDB, b.HoldID)
+	assert.Equal(t, user.ErrHoldOwnedByBet, err)
+	_, err = user.CaptureHold(ctx, test.MasterDB, b.HoldID, 0)
+	assert.Equal(t, user.ErrHoldOwnedByBet, err)
+
+	bets, err := user.SettleMarket(ctx, test.MasterDB, "match-4", map[string]string{"home": user.BetLose})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(bets))
+}
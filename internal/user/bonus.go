@@ -0,0 +1,395 @@
+package user
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+)
+
+// Bonus statuses. Only active bonuses can be staked and wagered. A bonus is
+// forfeited when its account is closed.
+const (
+	BonusActive    = "active"
+	BonusConverted = "converted"
+	BonusExpired   = "expired"
+	BonusForfeited = "forfeited"
+)
+
+var (
+	ErrBonusActive = errors.New("user already has an active bonus")
+)
+
+// Bonus is an amount granted to the user that can only be used for bets. It's
+// converted to cash once the user wagered Wagering times the amount on
+// settled bets, and the bonus funds left are forfeited if that doesn't happen
+// by ExpiresAt. A user has at most one active bonus, User.Bonus holds its
+// funds. Only bets placed here use and wager bonus funds, the debits of
+// seamless game providers are paid from cash and don't count towards
+// Wagering.
+type Bonus struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Amount    int64      `json:"amount"`
+	Wagering  int64      `json:"wagering"`
+	Required  int64      `json:"required"`
+	Wagered   int64      `json:"wagered"`
+	Status    string     `json:"status"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// GrantBonus credits the user's bonus balance with amount out of the house
+// account. It has to be wagered wagering times before expiresAt.
+func GrantBonus(ctx context.Context, dbConn *db.DB, userID string, amount, wagering int64, expiresAt time.Time) (*Bonus, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	now := time.Now()
+
+	user, err := get(txn, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := user.canReceive(); err != nil {
+		return nil, err
+	}
+
+	_, found, err := activeBonus(txn, userID)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return nil, ErrBonusActive
+	}
+
+	b := Bonus{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Amount:    amount,
+		Wagering:  wagering,
+		Required:  amount * wagering,
+		Status:    BonusActive,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}
+
+	user.Bonus = user.Bonus + amount
+
+	if err := txn.Insert("user", user); err != nil {
+		return nil, errors.Wrap(err, "txn.Insert")
+	}
+	if err := txn.Insert("bonus", b); err != nil {
+		return nil, errors.Wrap(err, "txn.Insert")
+	}
+
+	_, err = ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypeBonus,
+		UserID: userID,
+		Amount: amount,
+		Postings: []ledger.Posting{
+			{AccountID: ledger.BonusAccount(userID), Amount: amount},
+			{AccountID: ledger.House, Amount: -amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "ledger.Record")
+	}
+
+	txn.Commit()
+
+	return &b, nil
+}
+
+// ListBonusesByID returns the user's bonuses, oldest first.
+func ListBonusesByID(ctx context.Context, dbConn *db.DB, userID string) ([]Bonus, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	if _, err := get(txn, userID); err != nil {
+		return nil, err
+	}
+
+	bonuses, err := listBonuses(txn, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return bonuses, nil
+}
+
+// ExpireBonuses forfeits the funds of every active bonus that expired by now
+// and returns how many there were.
+func ExpireBonuses(ctx context.Context, dbConn *db.DB, now time.Time) (int, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	it, err := txn.Get("bonus", "status", BonusActive)
+	if err != nil {
+		return 0, errors.Wrap(err, "txn.Get")
+	}
+
+	var expired []Bonus
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		b, ok := obj.(Bonus)
+		if !ok {
+			return 0, errors.New("couldn't type assert bonus")
+		}
+		if !now.Before(b.ExpiresAt) {
+			expired = append(expired, b)
+		}
+	}
+
+	for _, b := range expired {
+		if err := closeBonus(txn, b, BonusExpired, now); err != nil {
+			return 0, errors.Wrap(err, "")
+		}
+	}
+
+	txn.Commit()
+
+	return len(expired), nil
+}
+
+func listBonuses(txn *memdb.Txn, userID string) ([]Bonus, error) {
+	it, err := txn.Get("bonus", "user_id", userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "txn.Get")
+	}
+
+	bonuses := []Bonus{}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		b, ok := obj.(Bonus)
+		if !ok {
+			return nil, errors.New("couldn't type assert bonus")
+		}
+		bonuses = append(bonuses, b)
+	}
+
+	sort.Slice(bonuses, func(i, j int) bool {
+		return bonuses[i].CreatedAt.Before(bonuses[j].CreatedAt)
+	})
+
+	return bonuses, nil
+}
+
+func getBonus(txn *memdb.Txn, bonusID string) (Bonus, bool, error) {
+	raw, err := txn.First("bonus", "id", bonusID)
+	if err != nil {
+		return Bonus{}, false, errors.Wrap(err, "txn.First")
+	}
+	if raw == nil {
+		return Bonus{}, false, nil
+	}
+
+	b, ok := raw.(Bonus)
+	if !ok {
+		return Bonus{}, false, errors.New("couldn't type assert bonus")
+	}
+
+	return b, true, nil
+}
+
+func activeBonus(txn *memdb.Txn, userID string) (Bonus, bool, error) {
+	bonuses, err := listBonuses(txn, userID)
+	if err != nil {
+		return Bonus{}, false, err
+	}
+
+	for _, b := range bonuses {
+		if b.Status == BonusActive {
+			return b, true, nil
+		}
+	}
+
+	return Bonus{}, false, nil
+}
+
+// stakeBonus takes up to amount of the user's bonus funds for a bet placed at
+// now and returns how much it took and from which bonus.
+func stakeBonus(txn *memdb.Txn, userID string, amount int64, now time.Time) (int64, string, error) {
+	b, found, err := activeBonus(txn, userID)
+	if err != nil {
+		return 0, "", err
+	}
+	if !found || !now.Before(b.ExpiresAt) {
+		return 0, "", nil
+	}
+
+	user, err := get(txn, userID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if amount > user.Bonus {
+		amount = user.Bonus
+	}
+	if amount == 0 {
+		return 0, "", nil
+	}
+
+	user.Bonus = user.Bonus - amount
+
+	if err := txn.Insert("user", user); err != nil {
+		return 0, "", errors.Wrap(err, "txn.Insert")
+	}
+
+	_, err = ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypeBonusBet,
+		UserID: userID,
+		Amount: amount,
+		Postings: []ledger.Posting{
+			{AccountID: ledger.BonusAccount(userID), Amount: -amount},
+			{AccountID: ledger.House, Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return 0, "", errors.Wrap(err, "ledger.Record")
+	}
+
+	return amount, b.ID, nil
+}
+
+// creditBonus pays amount won or returned on the bonus funded part of a bet.
+// It goes back to the bonus balance while the bonus is active, to cash once
+// it's converted and stays with the house once it expired or was forfeited.
+func creditBonus(txn *memdb.Txn, userID, bonusID string, amount int64, now time.Time) error {
+	if amount == 0 {
+		return nil
+	}
+
+	b, found, err := getBonus(txn, bonusID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.Errorf("bonus %s not found", bonusID)
+	}
+
+	switch b.Status {
+	case BonusConverted:
+		return payout(txn, userID, amount, now)
+	case BonusExpired, BonusForfeited:
+		return nil
+	}
+
+	user, err := get(txn, userID)
+	if err != nil {
+		return err
+	}
+
+	user.Bonus = user.Bonus + amount
+
+	if err := txn.Insert("user", user); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	_, err = ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypePayout,
+		UserID: userID,
+		Amount: amount,
+		Postings: []ledger.Posting{
+			{AccountID: ledger.BonusAccount(userID), Amount: amount},
+			{AccountID: ledger.House, Amount: -amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return errors.Wrap(err, "ledger.Record")
+	}
+
+	return nil
+}
+
+// wager counts the stake of a settled bet towards the active bonus and
+// converts the bonus once its requirement is met.
+func wager(txn *memdb.Txn, userID string, stake int64, now time.Time) error {
+	b, found, err := activeBonus(txn, userID)
+	if err != nil {
+		return err
+	}
+	if !found || !now.Before(b.ExpiresAt) {
+		return nil
+	}
+
+	b.Wagered = b.Wagered + stake
+	if b.Wagered >= b.Required {
+		return closeBonus(txn, b, BonusConverted, now)
+	}
+
+	if err := txn.Insert("bonus", b); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	return nil
+}
+
+// forfeitBonus closes the active bonus of the user, if there's one, and gives
+// its funds back to the house.
+func forfeitBonus(txn *memdb.Txn, userID string, now time.Time) error {
+	b, found, err := activeBonus(txn, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	return closeBonus(txn, b, BonusForfeited, now)
+}
+
+// closeBonus moves the bonus funds left to cash when the bonus is converted
+// and to the house otherwise.
+func closeBonus(txn *memdb.Txn, b Bonus, status string, now time.Time) error {
+	user, err := get(txn, b.UserID)
+	if err != nil {
+		return err
+	}
+
+	amount := user.Bonus
+	to, typ := ledger.House, ledger.TypeBonusExpire
+	if status == BonusConverted {
+		to, typ = b.UserID, ledger.TypeBonusConvert
+		user.Balance = user.Balance + amount
+	}
+	user.Bonus = 0
+
+	b.Status = status
+	b.ClosedAt = &now
+
+	if err := txn.Insert("user", user); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+	if err := txn.Insert("bonus", b); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	if amount == 0 {
+		return nil
+	}
+
+	_, err = ledger.Record(txn, ledger.Transaction{
+		Type:   typ,
+		UserID: b.UserID,
+		Amount: amount,
+		Postings: []ledger.Posting{
+			{AccountID: ledger.BonusAccount(b.UserID), Amount: -amount},
+			{AccountID: to, Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return errors.Wrap(err, "ledger.Record")
+	}
+
+	return nil
+}
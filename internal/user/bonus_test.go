@@ -0,0 +1,168 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+func TestBonus(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	t.Run("bonusCashFirst", bonusCashFirst)
+	t.Run("bonusConvert", bonusConvert)
+	t.Run("bonusExpire", bonusExpire)
+	t.Run("bonusForfeitOnClose", bonusForfeitOnClose)
+	t.Run("bonusNotForProviders", bonusNotForProviders)
+}
+
+func bonusUser(t *testing.T, cash, bonus, wagering int64) string {
+	userID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Kim"})
+	assert.NoError(t, err)
+	if cash > 0 {
+		_, err = user.DepositByID(ctx, test.MasterDB, userID, cash)
+		assert.NoError(t, err)
+	}
+	_, err = user.GrantBonus(ctx, test.MasterDB, userID, bonus, wagering, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	return userID
+}
+
+func bonusCashFirst(t *testing.T) {
+	userID := bonusUser(t, 100, 200, 5)
+
+	_, err := user.GrantBonus(ctx, test.MasterDB, userID, 50, 1, time.Now().Add(time.Hour))
+	assert.Equal(t, user.ErrBonusActive, err)
+
+	b, err := user.PlaceBet(ctx, test.MasterDB, user.Bet{UserID: userID, Market: "bonus-1", Selection: "home", Stake: 150, Odds: 200})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), b.BonusStake)
+
+	u, err := user.GetByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), u.Available())
+	assert.Equal(t, int64(150), u.Bonus)
+
+	// the bonus can't be withdrawn
+	_, err = user.WithdrawByID(ctx, test.MasterDB, userID, 150)
+	assert.Equal(t, user.ErrInsufficientFunds, err)
+
+	// winnings follow the funds the stake was paid with
+	_, err = user.SettleBet(ctx, test.MasterDB, b.ID, user.BetWin)
+	assert.NoError(t, err)
+
+	u, err = user.GetByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), u.Balance)
+	assert.Equal(t, int64(250), u.Bonus)
+}
+
+func bonusConvert(t *testing.T) {
+	userID := bonusUser(t, 0, 100, 2)
+
+	b, err := user.PlaceBet(ctx, test.MasterDB, user.Bet{UserID: userID, Market: "bonus-2", Selection: "home", Stake: 100, Odds: 300})
+	assert.NoError(t, err)
+	_, err = user.SettleBet(ctx, test.MasterDB, b.ID, user.BetWin)
+	assert.NoError(t, err)
+
+	b, err = user.PlaceBet(ctx, test.MasterDB, user.Bet{UserID: userID, Market: "bonus-2", Selection: "away", Stake: 100, Odds: 300})
+	assert.NoError(t, err)
+	_, err = user.SettleBet(ctx, test.MasterDB, b.ID, user.BetLose)
+	assert.NoError(t, err)
+
+	u, err := user.GetByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), u.Balance)
+	assert.Equal(t, int64(0), u.Bonus)
+
+	bonuses, err := user.ListBonusesByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(bonuses))
+	assert.Equal(t, user.BonusConverted, bonuses[0].Status)
+}
+
+func bonusExpire(t *testing.T) {
+	userID := bonusUser(t, 100, 100, 10)
+
+	n, err := user.ExpireBonuses(ctx, test.MasterDB, time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, n >= 1)
+
+	u, err := user.GetByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), u.Balance)
+	assert.Equal(t, int64(0), u.Bonus)
+
+	bonuses, err := user.ListBonusesByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, user.BonusExpired, bonuses[0].Status)
+}
+
+func bonusForfeitOnClose(t *testing.T) {
+	closedID := bonusUser(t, 0, 100, 10)
+	deletedID := bonusUser(t, 0, 100, 10)
+
+	err := user.SetStatusByID(ctx, test.MasterDB, closedID, user.StatusClosed)
+	assert.NoError(t, err)
+	err = user.DeleteByID(ctx, test.MasterDB, deletedID)
+	assert.NoError(t, err)
+
+	u, err := user.GetByID(ctx, test.MasterDB, closedID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), u.Bonus)
+
+	txn := test.MasterDB.Txn(tests.Context(), false)
+	defer txn.Abort()
+
+	for _, userID := range []string{closedID, deletedID} {
+		bonuses, err := user.ListBonusesByID(ctx, test.MasterDB, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, user.BonusForfeited, bonuses[0].Status)
+
+		// nothing is left on the ledger's bonus account
+		balance, err := ledger.BalanceAt(txn, ledger.BonusAccount(userID), time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), balance)
+	}
+}
+
+func bonusNotForProviders(t *testing.T) {
+	userID := bonusUser(t, 100, 200, 1)
+
+	debit := func(id string, amount int64) error {
+		_, err := user.ProcessProviderTxn(ctx, test.MasterDB, user.ProviderTxn{
+			Provider:      "acme",
+			TransactionID: id,
+			RoundID:       "round-" + id,
+			UserID:        userID,
+			Type:          user.ProviderDebit,
+			Amount:        amount,
+		})
+		return err
+	}
+
+	// debits don't use bonus funds
+	err := debit("bonus-d-1", 150)
+	assert.Equal(t, user.ErrInsufficientFunds, err)
+
+	// nor count towards wagering
+	err = debit("bonus-d-2", 100)
+	assert.NoError(t, err)
+
+	bs, err := user.ListBonusesByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	if assert.Len(t, bs, 1) {
+		assert.Equal(t, user.BonusActive, bs[0].Status)
+		assert.Equal(t, int64(0), bs[0].Wagered)
+	}
+
+	u, err := user.GetByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), u.Balance)
+	assert.Equal(t, int64(200), u.Bonus)
+}
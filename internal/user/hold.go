@@ -0,0 +1,308 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/metrics"
+)
+
+// DefaultHoldTTL is how long a hold lasts when it's created without one.
+const DefaultHoldTTL = 15 * time.Minute
+
+// Hold statuses. Only active holds reserve funds.
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldVoided   = "voided"
+	HoldExpired  = "expired"
+)
+
+var (
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is not active")
+	ErrCaptureExceedsHold = errors.New("capture exceeds held amount")
+	ErrHoldOwnedByBet     = errors.New("hold is owned by a bet")
+)
+
+// Hold reserves part of a user's balance. The funds stay in the balance but
+// can't be withdrawn or transferred until the hold is captured, voided or
+// expires. Holds of the stake of bet BetID are only captured or released by
+// settling the bet.
+type Hold struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Amount    int64     `json:"amount"`
+	Captured  int64     `json:"captured,omitempty"`
+	Status    string    `json:"status"`
+	Reference string    `json:"reference,omitempty"`
+	BetID     string    `json:"bet_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateHold reserves amount of the user's available balance for ttl.
+func CreateHold(ctx context.Context, dbConn *db.DB, userID string, amount int64, ttl time.Duration, reference string) (*Hold, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	h, err := createHold(txn, userID, amount, ttl, reference, "", time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	txn.Commit()
+
+	return &h, nil
+}
+
+func GetHoldByID(ctx context.Context, dbConn *db.DB, holdID string) (*Hold, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	h, err := getHold(txn, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+// CaptureHold takes amount of the held funds from the user and releases the
+// rest. Zero captures the whole hold.
+func CaptureHold(ctx context.Context, dbConn *db.DB, holdID string, amount int64) (*Hold, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	if err := notOwnedByBet(txn, holdID); err != nil {
+		return nil, err
+	}
+
+	h, err := captureHold(txn, holdID, amount, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	txn.Commit()
+
+	return &h, nil
+}
+
+// VoidHold releases the held funds back to the user's available balance.
+func VoidHold(ctx context.Context, dbConn *db.DB, holdID string) (*Hold, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	if err := notOwnedByBet(txn, holdID); err != nil {
+		return nil, err
+	}
+
+	h, err := releaseHold(txn, holdID, HoldVoided)
+	if err != nil {
+		return nil, err
+	}
+
+	txn.Commit()
+
+	return &h, nil
+}
+
+// ExpireHolds releases every active hold that expired by now and returns how
+// many there were.
+func ExpireHolds(ctx context.Context, dbConn *db.DB, now time.Time) (int, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	it, err := txn.Get("hold", "status", HoldActive)
+	if err != nil {
+		return 0, errors.Wrap(err, "txn.Get")
+	}
+
+	var expired []string
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		h, ok := obj.(Hold)
+		if !ok {
+			return 0, errors.New("couldn't type assert hold")
+		}
+		if !now.Before(h.ExpiresAt) {
+			expired = append(expired, h.ID)
+		}
+	}
+
+	for _, id := range expired {
+		if _, err := releaseHold(txn, id, HoldExpired); err != nil {
+			return 0, errors.Wrap(err, "")
+		}
+	}
+
+	txn.Commit()
+
+	return len(expired), nil
+}
+
+func getHold(txn *memdb.Txn, holdID string) (Hold, error) {
+	raw, err := txn.First("hold", "id", holdID)
+	if err != nil {
+		return Hold{}, errors.Wrap(err, "txn.First")
+	}
+	if raw == nil {
+		return Hold{}, ErrHoldNotFound
+	}
+
+	h, ok := raw.(Hold)
+	if !ok {
+		return Hold{}, errors.New("couldn't type assert hold")
+	}
+
+	return h, nil
+}
+
+// activeHold returns the hold if it can still be captured or voided at now.
+func activeHold(txn *memdb.Txn, holdID string, now time.Time) (Hold, error) {
+	h, err := getHold(txn, holdID)
+	if err != nil {
+		return h, err
+	}
+
+	if h.Status != HoldActive || !now.Before(h.ExpiresAt) {
+		return h, ErrHoldNotActive
+	}
+
+	return h, nil
+}
+
+// notOwnedByBet returns ErrHoldOwnedByBet when the hold with holdID holds the
+// stake of a bet.
+func notOwnedByBet(txn *memdb.Txn, holdID string) error {
+	h, err := getHold(txn, holdID)
+	if err != nil {
+		return err
+	}
+	if h.BetID != "" {
+		return ErrHoldOwnedByBet
+	}
+
+	return nil
+}
+
+func createHold(txn *memdb.Txn, userID string, amount int64, ttl time.Duration, reference, betID string, now time.Time) (Hold, error) {
+	user, err := get(txn, userID)
+	if err != nil {
+		return Hold{}, err
+	}
+
+	if err := user.canSend(); err != nil {
+		return Hold{}, err
+	}
+
+	if amount > user.Available() {
+		metrics.InsufficientFunds("hold")
+		return Hold{}, ErrInsufficientFunds
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultHoldTTL
+	}
+
+	h := Hold{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Amount:    amount,
+		Status:    HoldActive,
+		Reference: reference,
+		BetID:     betID,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+
+	user.Held = user.Held + amount
+
+	if err := txn.Insert("user", user); err != nil {
+		return Hold{}, errors.Wrap(err, "txn.Insert")
+	}
+	if err := txn.Insert("hold", h); err != nil {
+		return Hold{}, errors.Wrap(err, "txn.Insert")
+	}
+
+	return h, nil
+}
+
+func captureHold(txn *memdb.Txn, holdID string, amount int64, now time.Time) (Hold, error) {
+	h, err := activeHold(txn, holdID, now)
+	if err != nil {
+		return h, err
+	}
+
+	if amount == 0 {
+		amount = h.Amount
+	}
+	if amount > h.Amount {
+		return h, ErrCaptureExceedsHold
+	}
+
+	user, err := get(txn, h.UserID)
+	if err != nil {
+		return h, err
+	}
+
+	user.Held = user.Held - h.Amount
+	user.Balance = user.Balance - amount
+	h.Captured = amount
+	h.Status = HoldCaptured
+
+	if err := txn.Insert("user", user); err != nil {
+		return h, errors.Wrap(err, "txn.Insert")
+	}
+	if err := txn.Insert("hold", h); err != nil {
+		return h, errors.Wrap(err, "txn.Insert")
+	}
+
+	_, err = ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypeCapture,
+		UserID: h.UserID,
+		Amount: amount,
+		Postings: []ledger.Posting{
+			{AccountID: h.UserID, Amount: -amount},
+			{AccountID: ledger.House, Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return h, errors.Wrap(err, "ledger.Record")
+	}
+
+	return h, nil
+}
+
+func releaseHold(txn *memdb.Txn, holdID string, status string) (Hold, error) {
+	h, err := getHold(txn, holdID)
+	if err != nil {
+		return h, err
+	}
+
+	if h.Status != HoldActive {
+		return h, ErrHoldNotActive
+	}
+
+	user, err := get(txn, h.UserID)
+	if err != nil {
+		return h, err
+	}
+
+	user.Held = user.Held - h.Amount
+	h.Status = status
+
+	if err := txn.Insert("user", user); err != nil {
+		return h, errors.Wrap(err, "txn.Insert")
+	}
+	if err := txn.Insert("hold", h); err != nil {
+		return h, errors.Wrap(err, "txn.Insert")
+	}
+
+	return h, nil
+}
@@ -0,0 +1,96 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var holdUserID string
+
+func TestHold(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	var err error
+	holdUserID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, holdUserID, 1000)
+	assert.NoError(t, err)
+
+	t.Run("holdReducesAvailable", holdReducesAvailable)
+	t.Run("holdCapturePartial", holdCapturePartial)
+	t.Run("holdVoid", holdVoid)
+	t.Run("holdExpire", holdExpire)
+}
+
+func holdReducesAvailable(t *testing.T) {
+	h, err := user.CreateHold(ctx, test.MasterDB, holdUserID, 800, time.Minute, "round-1")
+	assert.NoError(t, err)
+	defer user.VoidHold(ctx, test.MasterDB, h.ID)
+
+	u, err := user.GetByID(ctx, test.MasterDB, holdUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), u.Balance, "holds don't change the balance")
+	assert.Equal(t, int64(200), u.Available())
+
+	_, err = user.WithdrawByID(ctx, test.MasterDB, holdUserID, 300)
+	assert.Equal(t, user.ErrInsufficientFunds, err)
+
+	_, err = user.CreateHold(ctx, test.MasterDB, holdUserID, 300, time.Minute, "")
+	assert.Equal(t, user.ErrInsufficientFunds, err)
+}
+
+func holdCapturePartial(t *testing.T) {
+	h, err := user.CreateHold(ctx, test.MasterDB, holdUserID, 500, time.Minute, "")
+	assert.NoError(t, err)
+
+	_, err = user.CaptureHold(ctx, test.MasterDB, h.ID, 600)
+	assert.Equal(t, user.ErrCaptureExceedsHold, err)
+
+	h, err = user.CaptureHold(ctx, test.MasterDB, h.ID, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, user.HoldCaptured, h.Status)
+	assert.Equal(t, int64(200), h.Captured)
+
+	u, err := user.GetByID(ctx, test.MasterDB, holdUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(800), u.Balance)
+	assert.Equal(t, int64(0), u.Held, "the rest of the hold should be released")
+
+	_, err = user.CaptureHold(ctx, test.MasterDB, h.ID, 0)
+	assert.Equal(t, user.ErrHoldNotActive, err)
+}
+
+func holdVoid(t *testing.T) {
+	h, err := user.CreateHold(ctx, test.MasterDB, holdUserID, 500, time.Minute, "")
+	assert.NoError(t, err)
+
+	h, err = user.VoidHold(ctx, test.MasterDB, h.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, user.HoldVoided, h.Status)
+
+	u, err := user.GetByID(ctx, test.MasterDB, holdUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(800), u.Available())
+}
+
+func holdExpire(t *testing.T) {
+	h, err := user.CreateHold(ctx, test.MasterDB, holdUserID, 500, time.Minute, "")
+	assert.NoError(t, err)
+
+	n, err := user.ExpireHolds(ctx, test.MasterDB, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	h, err = user.GetHoldByID(ctx, test.MasterDB, h.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, user.HoldExpired, h.Status)
+
+	u, err := user.GetByID(ctx, test.MasterDB, holdUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(800), u.Available())
+}
@@ -0,0 +1,81 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+)
+
+// Opening is a user migrated from another wallet with the balance they
+// bring along.
+type Opening struct {
+	User    User
+	Balance int64
+}
+
+// ImportBatch creates the users with their opening balances in a single
+// transaction. Opening balances are money that already went through the
+// limits and risk rules of the other wallet, so they're recorded as imports
+// rather than deposits. A user that can't be created gets its error at the
+// same index and is skipped, the others are committed unless dryRun is set.
+func ImportBatch(ctx context.Context, dbConn *db.DB, openings []Opening, dryRun bool) ([]error, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	now := time.Now()
+
+	errs := make([]error, len(openings))
+	for i, o := range openings {
+		err := importOpening(txn, o, now)
+		switch errors.Cause(err) {
+		case nil:
+		case ErrEmailTaken:
+			errs[i] = err
+		default:
+			return nil, err
+		}
+	}
+
+	if !dryRun {
+		txn.Commit()
+	}
+
+	return errs, nil
+}
+
+func importOpening(txn *memdb.Txn, o Opening, now time.Time) error {
+	u, err := insert(txn, o.User, now)
+	if err != nil {
+		return err
+	}
+
+	if o.Balance == 0 {
+		return nil
+	}
+
+	u.Balance = o.Balance
+
+	if err := txn.Insert("user", u); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	_, err = ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypeImport,
+		UserID: u.ID,
+		Amount: o.Balance,
+		Postings: []ledger.Posting{
+			{AccountID: u.ID, Amount: o.Balance},
+			{AccountID: ledger.External, Amount: -o.Balance},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return errors.Wrap(err, "ledger.Record")
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/metrics"
+)
+
+// Calls an external game provider makes to the seamless wallet. A debit takes
+// a bet, a credit pays a win and a rollback cancels a debit. Debits only take
+// cash and aren't wagered towards a bonus, see Bonus.
+const (
+	ProviderDebit    = "debit"
+	ProviderCredit   = "credit"
+	ProviderRollback = "rollback"
+)
+
+var (
+	ErrInvalidProviderTxn  = errors.New("invalid provider transaction type")
+	ErrProviderTxnConflict = errors.New("provider transaction id reused with different details")
+)
+
+// ProviderTxn is a call from a game provider, identified by the provider and
+// its own transaction id. Repeating a call returns the stored result instead
+// of moving funds again. RollbackOf is the transaction id of the debit a
+// rollback cancels. A rollback that arrives before its debit is kept, and the
+// debit is then accepted as rolled back without moving any funds, unless it's
+// for another user or round. Balance is the user's available balance right
+// after the call.
+type ProviderTxn struct {
+	Provider      string    `json:"provider"`
+	TransactionID string    `json:"transaction_id"`
+	RoundID       string    `json:"round_id"`
+	UserID        string    `json:"user_id"`
+	Type          string    `json:"type"`
+	Amount        int64     `json:"amount"`
+	RollbackOf    string    `json:"rollback_of,omitempty"`
+	LedgerID      string    `json:"ledger_id,omitempty"`
+	RolledBack    bool      `json:"rolled_back,omitempty"`
+	Balance       int64     `json:"balance"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ProcessProviderTxn applies a debit, credit or rollback from a game provider
+// exactly once.
+func ProcessProviderTxn(ctx context.Context, dbConn *db.DB, pt ProviderTxn) (*ProviderTxn, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	pt, err := processProviderTxn(txn, pt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	txn.Commit()
+
+	return &pt, nil
+}
+
+func processProviderTxn(txn *memdb.Txn, pt ProviderTxn, now time.Time) (ProviderTxn, error) {
+	prev, found, err := getProviderTxn(txn, pt.Provider, pt.TransactionID)
+	if err != nil {
+		return pt, err
+	}
+	if found {
+		if !prev.sameCall(pt) {
+			return pt, ErrProviderTxnConflict
+		}
+		return prev, nil
+	}
+
+	pt.LedgerID = ""
+	pt.RolledBack = false
+
+	switch pt.Type {
+	case ProviderDebit:
+		pt, err = providerDebit(txn, pt, now)
+	case ProviderCredit:
+		pt, err = providerCredit(txn, pt, now)
+	case ProviderRollback:
+		err = providerRollback(txn, pt, now)
+	default:
+		err = ErrInvalidProviderTxn
+	}
+	if err != nil {
+		return pt, err
+	}
+
+	user, err := get(txn, pt.UserID)
+	if err != nil {
+		return pt, err
+	}
+
+	pt.Balance = user.Available()
+	pt.CreatedAt = now
+
+	if err := txn.Insert("provider_txn", pt); err != nil {
+		return pt, errors.Wrap(err, "txn.Insert")
+	}
+
+	return pt, nil
+}
+
+// sameCall reports whether pt repeats the call that was stored as p.
+func (p ProviderTxn) sameCall(pt ProviderTxn) bool {
+	return p.UserID == pt.UserID &&
+		p.Type == pt.Type &&
+		p.RoundID == pt.RoundID &&
+		p.Amount == pt.Amount &&
+		p.RollbackOf == pt.RollbackOf
+}
+
+// cancels reports whether the rollback p is about the same user as the debit
+// and, when both name one, the same round.
+func (p ProviderTxn) cancels(debit ProviderTxn) bool {
+	if p.UserID != debit.UserID {
+		return false
+	}
+	return p.RoundID == "" || debit.RoundID == "" || p.RoundID == debit.RoundID
+}
+
+func getProviderTxn(txn *memdb.Txn, provider, transactionID string) (ProviderTxn, bool, error) {
+	raw, err := txn.First("provider_txn", "id", provider, transactionID)
+	if err != nil {
+		return ProviderTxn{}, false, errors.Wrap(err, "txn.First")
+	}
+	if raw == nil {
+		return ProviderTxn{}, false, nil
+	}
+
+	pt, ok := raw.(ProviderTxn)
+	if !ok {
+		return ProviderTxn{}, false, errors.New("couldn't type assert provider transaction")
+	}
+
+	return pt, true, nil
+}
+
+func providerDebit(txn *memdb.Txn, pt ProviderTxn, now time.Time) (ProviderTxn, error) {
+	// The rollback got here first, the debit is void.
+	raw, err := txn.First("provider_txn", "rollback_of", pt.Provider, pt.TransactionID)
+	if err != nil {
+		return pt, errors.Wrap(err, "txn.First")
+	}
+	if raw != nil {
+		rb, ok := raw.(ProviderTxn)
+		if !ok {
+			return pt, errors.New("couldn't type assert provider transaction")
+		}
+		if !rb.cancels(pt) {
+			return pt, ErrProviderTxnConflict
+		}
+
+		pt.RolledBack = true
+		return pt, nil
+	}
+
+	user, err := get(txn, pt.UserID)
+	if err != nil {
+		return pt, err
+	}
+
+	if err := user.canSend(); err != nil {
+		return pt, err
+	}
+
+	if pt.Amount > user.Available() {
+		metrics.InsufficientFunds(ledger.TypeDebit)
+		return pt, ErrInsufficientFunds
+	}
+
+	user.Balance = user.Balance - pt.Amount
+
+	if err := txn.Insert("user", user); err != nil {
+		return pt, errors.Wrap(err, "txn.Insert")
+	}
+
+	t, err := ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypeDebit,
+		UserID: pt.UserID,
+		Amount: pt.Amount,
+		Postings: []ledger.Posting{
+			{AccountID: pt.UserID, Amount: -pt.Amount},
+			{AccountID: ledger.House, Amount: pt.Amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return pt, errors.Wrap(err, "ledger.Record")
+	}
+	pt.LedgerID = t.ID
+
+	return pt, nil
+}
+
+func providerCredit(txn *memdb.Txn, pt ProviderTxn, now time.Time) (ProviderTxn, error) {
+	user, err := get(txn, pt.UserID)
+	if err != nil {
+		return pt, err
+	}
+
+	if err := user.canReceive(); err != nil {
+		return pt, err
+	}
+
+	user.Balance = user.Balance + pt.Amount
+
+	if err := txn.Insert("user", user); err != nil {
+		return pt, errors.Wrap(err, "txn.Insert")
+	}
+
+	t, err := ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypeCredit,
+		UserID: pt.UserID,
+		Amount: pt.Amount,
+		Postings: []ledger.Posting{
+			{AccountID: pt.UserID, Amount: pt.Amount},
+			{AccountID: ledger.House, Amount: -pt.Amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return pt, errors.Wrap(err, "ledger.Record")
+	}
+	pt.LedgerID = t.ID
+
+	return pt, nil
+}
+
+func providerRollback(txn *memdb.Txn, pt ProviderTxn, now time.Time) error {
+	debit, found, err := getProviderTxn(txn, pt.Provider, pt.RollbackOf)
+	if err != nil {
+		return err
+	}
+	if !found {
+		// Stored as is, the debit is voided when it arrives.
+		return nil
+	}
+
+	if debit.Type != ProviderDebit {
+		return ErrNotReversible
+	}
+	if !pt.cancels(debit) {
+		return ErrProviderTxnConflict
+	}
+	if debit.RolledBack {
+		return nil
+	}
+
+	// A debit that's already reversed has been refunded, so the rollback is
+	// done.
+	if debit.LedgerID != "" {
+		if _, err := reverse(txn, debit.LedgerID, 0, now); err != nil && err != ErrAlreadyReversed {
+			return err
+		}
+	}
+
+	debit.RolledBack = true
+
+	if err := txn.Insert("provider_txn", debit); err != nil {
+		return errors.Wrap(err, "txn.Insert")
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package user_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var providerUserID string
+
+func TestProviderTxn(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	var err error
+	providerUserID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Sam"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, providerUserID, 1000)
+	assert.NoError(t, err)
+
+	t.Run("providerDebitRollback", providerDebitRollback)
+	t.Run("providerInsufficientFunds", providerInsufficientFunds)
+	t.Run("providerRollbackCredit", providerRollbackCredit)
+	t.Run("providerRollbackFirstMismatch", providerRollbackFirstMismatch)
+	t.Run("providerRollbackReversed", providerRollbackReversed)
+}
+
+func providerTxn(typ, id string, amount int64) user.ProviderTxn {
+	return user.ProviderTxn{
+		Provider:      "acme",
+		TransactionID: id,
+		RoundID:       "round-" + id,
+		UserID:        providerUserID,
+		Type:          typ,
+		Amount:        amount,
+	}
+}
+
+func providerDebitRollback(t *testing.T) {
+	pt, err := user.ProcessProviderTxn(ctx, test.MasterDB, providerTxn(user.ProviderDebit, "d-1", 250))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(750), pt.Balance)
+
+	rollback := providerTxn(user.ProviderRollback, "rb-1", 0)
+	rollback.RoundID = "round-d-1"
+	rollback.RollbackOf = "d-1"
+	pt, err = user.ProcessProviderTxn(ctx, test.MasterDB, rollback)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), pt.Balance)
+
+	// a second rollback of the same debit doesn't refund it twice
+	rollback.TransactionID = "rb-1-again"
+	pt, err = user.ProcessProviderTxn(ctx, test.MasterDB, rollback)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), pt.Balance)
+}
+
+func providerInsufficientFunds(t *testing.T) {
+	_, err := user.ProcessProviderTxn(ctx, test.MasterDB, providerTxn(user.ProviderDebit, "d-2", 5000))
+	assert.Equal(t, user.ErrInsufficientFunds, err)
+
+	// a failed call isn't stored and can be retried
+	_, err = user.ProcessProviderTxn(ctx, test.MasterDB, providerTxn(user.ProviderDebit, "d-2", 500))
+	assert.NoError(t, err)
+}
+
+func providerRollbackCredit(t *testing.T) {
+	_, err := user.ProcessProviderTxn(ctx, test.MasterDB, providerTxn(user.ProviderCredit, "c-3", 100))
+	assert.NoError(t, err)
+
+	rollback := providerTxn(user.ProviderRollback, "rb-3", 0)
+	rollback.RollbackOf = "c-3"
+	_, err = user.ProcessProviderTxn(ctx, test.MasterDB, rollback)
+	assert.Equal(t, user.ErrNotReversible, err)
+}
+
+func providerRollbackFirstMismatch(t *testing.T) {
+	otherID, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Eve"})
+	assert.NoError(t, err)
+	_, err = user.DepositByID(ctx, test.MasterDB, otherID, 1000)
+	assert.NoError(t, err)
+
+	// a rollback for another player's debit, sent before it
+	rollback := providerTxn(user.ProviderRollback, "rb-4", 0)
+	rollback.RoundID = "round-d-4"
+	rollback.RollbackOf = "d-4"
+	_, err = user.ProcessProviderTxn(ctx, test.MasterDB, rollback)
+	assert.NoError(t, err)
+
+	debit := providerTxn(user.ProviderDebit, "d-4", 100)
+	debit.UserID = otherID
+	_, err = user.ProcessProviderTxn(ctx, test.MasterDB, debit)
+	assert.Equal(t, user.ErrProviderTxnConflict, err)
+
+	// nor one of another round of the same player
+	rollback = providerTxn(user.ProviderRollback, "rb-5", 0)
+	rollback.RoundID = "round-other"
+	rollback.RollbackOf = "d-5"
+	_, err = user.ProcessProviderTxn(ctx, test.MasterDB, rollback)
+	assert.NoError(t, err)
+
+	_, err = user.ProcessProviderTxn(ctx, test.MasterDB, providerTxn(user.ProviderDebit, "d-5", 100))
+	assert.Equal(t, user.ErrProviderTxnConflict, err)
+
+	// the matching debit is voided
+	rollback = providerTxn(user.ProviderRollback, "rb-6", 0)
+	rollback.RoundID = "round-d-6"
+	rollback.RollbackOf = "d-6"
+	_, err = user.ProcessProviderTxn(ctx, test.MasterDB, rollback)
+	assert.NoError(t, err)
+
+	pt, err := user.ProcessProviderTxn(ctx, test.MasterDB, providerTxn(user.ProviderDebit, "d-6", 100))
+	assert.NoError(t, err)
+	assert.True(t, pt.RolledBack)
+}
+
+func providerRollbackReversed(t *testing.T) {
+	debit, err := user.ProcessProviderTxn(ctx, test.MasterDB, providerTxn(user.ProviderDebit, "d-7", 100))
+	assert.NoError(t, err)
+
+	// the debit was reversed in the ledger before the rollback came
+	txn := test.MasterDB.Txn(ctx, true)
+	orig, err := ledger.GetByID(txn, debit.LedgerID)
+	assert.NoError(t, err)
+	var postings []ledger.Posting
+	for _, p := range orig.Postings {
+		postings = append(postings, ledger.Posting{AccountID: p.AccountID, Amount: -p.Amount})
+	}
+	_, err = ledger.Record(txn, ledger.Transaction{
+		Type:       ledger.TypeReversal,
+		UserID:     providerUserID,
+		Amount:     orig.Amount,
+		Postings:   postings,
+		ReversalOf: orig.ID,
+	})
+	assert.NoError(t, err)
+	txn.Commit()
+
+	rollback := providerTxn(user.ProviderRollback, "rb-7", 0)
+	rollback.RoundID = "round-d-7"
+	rollback.RollbackOf = "d-7"
+	_, err = user.ProcessProviderTxn(ctx, test.MasterDB, rollback)
+	assert.NoError(t, err)
+
+	pt, err := user.ProcessProviderTxn(ctx, test.MasterDB, providerTxn(user.ProviderDebit, "d-7", 100))
+	assert.NoError(t, err)
+	assert.True(t, pt.RolledBack)
+}
@@ -0,0 +1,168 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/pkg/errors"
+	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/metrics"
+)
+
+var (
+	ErrAlreadyReversed       = errors.New("transaction is already reversed")
+	ErrNotReversible         = errors.New("transaction can't be reversed")
+	ErrReversalExceedsAmount = errors.New("reversal exceeds transaction amount")
+)
+
+func GetTransactionByID(ctx context.Context, dbConn *db.DB, transactionID string) (*ledger.Transaction, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	t, err := ledger.GetByID(txn, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// ListTransactionsByID returns the user's transactions, oldest first.
+func ListTransactionsByID(ctx context.Context, dbConn *db.DB, userID string) ([]ledger.Transaction, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	if _, err := get(txn, userID); err != nil {
+		return nil, err
+	}
+
+	ts, err := ledger.ListByAccount(txn, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return ts, nil
+}
+
+// reversible are the types of the transactions ReverseTransaction reverses.
+// The others belong to a hold, bet, round or bonus and are undone through it.
+var reversible = map[string]bool{
+	ledger.TypeDeposit:  true,
+	ledger.TypeWithdraw: true,
+	ledger.TypeTransfer: true,
+}
+
+// ReverseTransaction records a transaction compensating amount of the one
+// with transactionID, all of it when amount is zero. Partial reversals move
+// every posting proportionally. Only deposits, withdrawals and transfers are
+// reversed, each once, and a reversal can't take a user's available balance
+// below zero.
+func ReverseTransaction(ctx context.Context, dbConn *db.DB, transactionID string, amount int64) (*ledger.Transaction, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	orig, err := ledger.GetByID(txn, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if !reversible[orig.Type] {
+		return nil, ErrNotReversible
+	}
+
+	t, err := reverse(txn, transactionID, amount, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	txn.Commit()
+
+	return &t, nil
+}
+
+func reverse(txn *memdb.Txn, transactionID string, amount int64, now time.Time) (ledger.Transaction, error) {
+	orig, err := ledger.GetByID(txn, transactionID)
+	if err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	if orig.Type == ledger.TypeReversal || orig.Amount <= 0 {
+		return ledger.Transaction{}, ErrNotReversible
+	}
+
+	// Bonus funds follow the bonus rules and are never reversed.
+	for _, p := range orig.Postings {
+		if p.AccountID == ledger.BonusAccount(orig.UserID) {
+			return ledger.Transaction{}, ErrNotReversible
+		}
+	}
+
+	_, err = ledger.ReversalOf(txn, orig.ID)
+	switch errors.Cause(err) {
+	case nil:
+		return ledger.Transaction{}, ErrAlreadyReversed
+	case ledger.ErrNotFound:
+	default:
+		return ledger.Transaction{}, errors.Wrap(err, "")
+	}
+
+	if amount == 0 {
+		amount = orig.Amount
+	}
+	if amount > orig.Amount {
+		return ledger.Transaction{}, ErrReversalExceedsAmount
+	}
+
+	// Scale every posting down to the reversed amount and put whatever
+	// rounding left over on the last one, so they still balance.
+	postings := make([]ledger.Posting, len(orig.Postings))
+	var sum int64
+	for i, p := range orig.Postings {
+		postings[i] = ledger.Posting{
+			AccountID: p.AccountID,
+			Amount:    -p.Amount * amount / orig.Amount,
+		}
+		sum += postings[i].Amount
+	}
+	postings[len(postings)-1].Amount -= sum
+
+	for _, p := range postings {
+		user, err := get(txn, p.AccountID)
+		if errors.Cause(err) == ErrNotFound {
+			// System accounts don't have a stored balance.
+			continue
+		}
+		if err != nil {
+			return ledger.Transaction{}, err
+		}
+
+		if user.Status == StatusClosed {
+			return ledger.Transaction{}, ErrAccountClosed
+		}
+		if p.Amount < 0 && -p.Amount > user.Available() {
+			metrics.InsufficientFunds(ledger.TypeReversal)
+			return ledger.Transaction{}, ErrInsufficientFunds
+		}
+
+		user.Balance = user.Balance + p.Amount
+
+		if err := txn.Insert("user", user); err != nil {
+			return ledger.Transaction{}, errors.Wrap(err, "txn.Insert")
+		}
+	}
+
+	t, err := ledger.Record(txn, ledger.Transaction{
+		Type:       ledger.TypeReversal,
+		UserID:     orig.UserID,
+		Amount:     amount,
+		Postings:   postings,
+		ReversalOf: orig.ID,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "ledger.Record")
+	}
+
+	return t, nil
+}
@@ -0,0 +1,96 @@
+package user_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/tests"
+	"github.com/timurguseynov/go-wallet-api/internal/user"
+)
+
+var (
+	reversalUserID string
+	reversalToID   string
+)
+
+func TestReversal(t *testing.T) {
+	defer tests.Recover(t)
+	ctx = tests.Context()
+
+	var err error
+	reversalUserID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Alex"})
+	assert.NoError(t, err)
+	reversalToID, err = user.Insert(ctx, test.MasterDB, user.User{Name: "John"})
+	assert.NoError(t, err)
+
+	t.Run("reversalDeposit", reversalDeposit)
+	t.Run("reversalPartialTransfer", reversalPartialTransfer)
+	t.Run("reversalNegativeBalance", reversalNegativeBalance)
+}
+
+// lastTransaction returns the latest transaction of the user.
+func lastTransaction(t *testing.T, userID string) ledger.Transaction {
+	ts, err := user.ListTransactionsByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	return ts[len(ts)-1]
+}
+
+func reversalDeposit(t *testing.T) {
+	_, err := user.DepositByID(ctx, test.MasterDB, reversalUserID, 1000)
+	assert.NoError(t, err)
+	deposit := lastTransaction(t, reversalUserID)
+
+	rev, err := user.ReverseTransaction(ctx, test.MasterDB, deposit.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.TypeReversal, rev.Type)
+	assert.Equal(t, deposit.ID, rev.ReversalOf)
+	assert.Equal(t, int64(1000), rev.Amount)
+
+	balance, err := user.GetBalanceByID(ctx, test.MasterDB, reversalUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), balance)
+
+	_, err = user.ReverseTransaction(ctx, test.MasterDB, deposit.ID, 0)
+	assert.Equal(t, user.ErrAlreadyReversed, err)
+
+	_, err = user.ReverseTransaction(ctx, test.MasterDB, rev.ID, 0)
+	assert.Equal(t, user.ErrNotReversible, err)
+}
+
+func reversalPartialTransfer(t *testing.T) {
+	_, err := user.DepositByID(ctx, test.MasterDB, reversalUserID, 1000)
+	assert.NoError(t, err)
+	_, err = user.TransferByID(ctx, test.MasterDB, reversalUserID, reversalToID, 600)
+	assert.NoError(t, err)
+	transfer := lastTransaction(t, reversalUserID)
+
+	_, err = user.ReverseTransaction(ctx, test.MasterDB, transfer.ID, 601)
+	assert.Equal(t, user.ErrReversalExceedsAmount, err)
+
+	_, err = user.ReverseTransaction(ctx, test.MasterDB, transfer.ID, 200)
+	assert.NoError(t, err)
+
+	from, err := user.GetBalanceByID(ctx, test.MasterDB, reversalUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(600), from)
+
+	to, err := user.GetBalanceByID(ctx, test.MasterDB, reversalToID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(400), to)
+}
+
+func reversalNegativeBalance(t *testing.T) {
+	_, err := user.DepositByID(ctx, test.MasterDB, reversalToID, 500)
+	assert.NoError(t, err)
+	deposit := lastTransaction(t, reversalToID)
+
+	_, err = user.WithdrawByID(ctx, test.MasterDB, reversalToID, 800)
+	assert.NoError(t, err)
+
+	_, err = user.ReverseTransaction(ctx, test.MasterDB, deposit.ID, 0)
+	assert.Equal(t, user.ErrInsufficientFunds, err)
+
+	_, err = user.ReverseTransaction(ctx, test.MasterDB, "unknown", 0)
+	assert.Equal(t, ledger.ErrNotFound, err)
+}
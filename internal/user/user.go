@@ -3,30 +3,105 @@ package user
 import (
 	"context"
 	"sort"
+	"strings"
+	"time"
 
+	"github.com/asaskevich/govalidator"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
 	"github.com/google/uuid"
+	"github.com/hashicorp/go-memdb"
 	"github.com/pkg/errors"
 	"github.com/timurguseynov/go-wallet-api/internal/db"
+	"github.com/timurguseynov/go-wallet-api/internal/fee"
+	"github.com/timurguseynov/go-wallet-api/internal/ledger"
+	"github.com/timurguseynov/go-wallet-api/internal/limit"
+	"github.com/timurguseynov/go-wallet-api/internal/metrics"
+	"github.com/timurguseynov/go-wallet-api/internal/risk"
 )
 
 var (
+	ErrNotFound          = errors.New("user not found")
 	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrSameAccount       = errors.New("can't transfer to the same account")
+	ErrAccountFrozen     = errors.New("account is frozen")
+	ErrAccountSuspended  = errors.New("account is suspended")
+	ErrAccountClosed     = errors.New("account is closed")
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrBalanceNotZero    = errors.New("balance is not zero")
+	ErrEmailTaken        = errors.New("email is already taken")
+	ErrCurrencyMismatch  = errors.New("accounts have different currencies")
 )
 
+// DefaultCurrency is the currency of accounts created without one.
+const DefaultCurrency = "EUR"
+
+var currencyCode = validation.NewStringRule(govalidator.IsISO4217, "must be a valid currency code")
+
+// Account statuses. Frozen accounts can receive money but not send it,
+// suspended accounts can do neither and closed accounts can't be reopened.
+const (
+	StatusActive    = "active"
+	StatusFrozen    = "frozen"
+	StatusSuspended = "suspended"
+	StatusClosed    = "closed"
+)
+
+// transitions lists the statuses an account can move to from each status.
+var transitions = map[string][]string{
+	StatusActive:    {StatusFrozen, StatusSuspended, StatusClosed},
+	StatusFrozen:    {StatusActive, StatusSuspended, StatusClosed},
+	StatusSuspended: {StatusActive, StatusFrozen, StatusClosed},
+	StatusClosed:    {},
+}
+
 type User struct {
-	ID      string `json:"id,omitempty"`
-	Name    string `json:"name,omitempty"`
-	Balance int64  `json:"balance,omitempty"`
+	ID        string     `json:"id,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	Country   string     `json:"country,omitempty"`
+	Currency  string     `json:"currency,omitempty"`
+	Balance   int64      `json:"balance,omitempty"`
+	Held      int64      `json:"held,omitempty"`
+	Bonus     int64      `json:"bonus,omitempty"`
+	Status    string     `json:"status,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Validate checks the fields a client can set.
+func (u User) Validate() error {
+	return validation.ValidateStruct(&u, u.Rules()...)
 }
 
+// Rules are the rules of the fields a client can set.
+func (u *User) Rules() []*validation.FieldRules {
+	return []*validation.FieldRules{
+		validation.Field(&u.Name, validation.Required, validation.Length(1, 100)),
+		validation.Field(&u.Email, is.Email),
+		validation.Field(&u.Country, is.CountryCode2),
+		validation.Field(&u.Currency, currencyCode),
+	}
+}
+
+// Query filters and paginates the users returned by Search. Text matches
+// name or email case-insensitively.
+type Query struct {
+	Text   string
+	Status string
+	Limit  int
+	Offset int
+}
+
+// Insert creates the user. The balance always starts at zero and can only
+// be changed through the ledger. The currency can't be changed later.
 func Insert(ctx context.Context, dbConn *db.DB, u User) (string, error) {
-	txn := dbConn.Txn(true)
+	txn := dbConn.Txn(ctx, true)
 	defer txn.Abort()
 
-	u.ID = uuid.New().String()
-
-	if err := txn.Insert("user", u); err != nil {
-		return "", errors.Wrap(err, "committing transaction")
+	u, err := insert(txn, u, time.Now())
+	if err != nil {
+		return "", err
 	}
 
 	txn.Commit()
@@ -34,40 +109,109 @@ func Insert(ctx context.Context, dbConn *db.DB, u User) (string, error) {
 	return u.ID, nil
 }
 
+// GetByID returns the user, ErrNotFound if it doesn't exist or is deleted.
 func GetByID(ctx context.Context, dbConn *db.DB, userID string) (*User, error) {
-	txn := dbConn.Txn(true)
+	txn := dbConn.Txn(ctx, false)
 	defer txn.Abort()
 
-	raw, err := txn.First("user", "id", userID)
+	user, err := get(txn, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+
+	return &user, nil
+}
+
+// GetByEmail returns the user, ErrNotFound if it doesn't exist or is deleted.
+func GetByEmail(ctx context.Context, dbConn *db.DB, email string) (*User, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	raw, err := txn.First("user", "email", email)
 	if err != nil {
 		return nil, errors.Wrap(err, "txn.First")
 	}
+	if raw == nil {
+		return nil, ErrNotFound
+	}
 
 	user, ok := raw.(User)
 	if !ok {
 		return nil, errors.New("couldn't type assert user")
 	}
 
+	if user.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+
+	return &user, nil
+}
+
+// UpdateByID replaces the profile fields of the user with the ones in u.
+func UpdateByID(ctx context.Context, dbConn *db.DB, userID string, u User) (*User, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	user, err := get(txn, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DeletedAt != nil {
+		return nil, ErrAccountClosed
+	}
+
+	if err := checkEmail(txn, userID, u.Email); err != nil {
+		return nil, err
+	}
+
+	user.Name = u.Name
+	user.Email = u.Email
+	user.Country = u.Country
+
+	if err := txn.Insert("user", user); err != nil {
+		return nil, errors.Wrap(err, "txn.Insert")
+	}
+
 	txn.Commit()
 
 	return &user, nil
 }
 
-func DepositByID(ctx context.Context, dbConn *db.DB, userID string, amount int64) error {
-	txn := dbConn.Txn(true)
+// DeleteByID soft-deletes the user. The account is closed, so like closing
+// it requires a zero balance and forfeits the active bonus, and it's left out
+// of List and Search.
+func DeleteByID(ctx context.Context, dbConn *db.DB, userID string) error {
+	txn := dbConn.Txn(ctx, true)
 	defer txn.Abort()
 
-	raw, err := txn.First("user", "id", userID)
+	user, err := get(txn, userID)
 	if err != nil {
-		return errors.Wrap(err, "txn.First")
+		return err
 	}
 
-	user, ok := raw.(User)
-	if !ok {
-		return errors.New("couldn't type assert user")
+	if user.DeletedAt != nil {
+		return nil
 	}
 
-	user.Balance = user.Balance + amount
+	if user.Balance != 0 {
+		return ErrBalanceNotZero
+	}
+
+	now := time.Now()
+	if err := forfeitBonus(txn, userID, now); err != nil {
+		return err
+	}
+	if user, err = get(txn, userID); err != nil {
+		return err
+	}
+
+	user.Status = StatusClosed
+	user.DeletedAt = &now
 
 	if err := txn.Insert("user", user); err != nil {
 		return errors.Wrap(err, "txn.Insert")
@@ -78,25 +222,111 @@ func DepositByID(ctx context.Context, dbConn *db.DB, userID string, amount int64
 	return nil
 }
 
-func WithdrawByID(ctx context.Context, dbConn *db.DB, userID string, amount int64) error {
-	txn := dbConn.Txn(true)
+// Search returns the page of users matching q, oldest first, and the number
+// of users matching q in total.
+func Search(ctx context.Context, dbConn *db.DB, q Query) ([]User, int, error) {
+	users, err := List(ctx, dbConn)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "")
+	}
+
+	text := strings.ToLower(q.Text)
+	matched := []User{}
+	for _, u := range users {
+		if q.Status != "" && u.Status != q.Status {
+			continue
+		}
+		if text != "" &&
+			!strings.Contains(strings.ToLower(u.Name), text) &&
+			!strings.Contains(strings.ToLower(u.Email), text) {
+			continue
+		}
+		matched = append(matched, u)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].ID < matched[j].ID
+		}
+		return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+	})
+
+	total := len(matched)
+	if q.Offset > total {
+		q.Offset = total
+	}
+	end := total
+	if q.Limit > 0 && q.Offset+q.Limit < total {
+		end = q.Offset + q.Limit
+	}
+
+	return matched[q.Offset:end], total, nil
+}
+
+// DepositByID deposits amount into the user's cash. The transaction has the
+// risk decision it was recorded with.
+func DepositByID(ctx context.Context, dbConn *db.DB, userID string, amount int64) (*ledger.Transaction, error) {
+	txn := dbConn.Txn(ctx, true)
 	defer txn.Abort()
 
-	raw, err := txn.First("user", "id", userID)
+	t, err := deposit(txn, userID, amount, time.Now())
 	if err != nil {
-		return errors.Wrap(err, "txn.First")
+		metrics.Operation(ledger.TypeDeposit, outcome(err), amount)
+		return nil, err
 	}
 
-	user, ok := raw.(User)
-	if !ok {
-		return errors.New("couldn't type assert user")
+	txn.Commit()
+	metrics.Operation(ledger.TypeDeposit, metrics.OutcomeOK, amount)
+
+	return &t, nil
+}
+
+// WithdrawByID withdraws amount and the fee for it from the user's cash.
+func WithdrawByID(ctx context.Context, dbConn *db.DB, userID string, amount int64) (*ledger.Transaction, error) {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	t, err := withdraw(txn, userID, amount, time.Now())
+	if err != nil {
+		metrics.Operation(ledger.TypeWithdraw, outcome(err), amount)
+		return nil, err
+	}
+
+	txn.Commit()
+	metrics.Operation(ledger.TypeWithdraw, metrics.OutcomeOK, amount)
+
+	return &t, nil
+}
+
+// SetStatusByID moves the account to status if the state machine allows it.
+// Closing requires a zero balance and forfeits the active bonus, its funds go
+// back to the house.
+func SetStatusByID(ctx context.Context, dbConn *db.DB, userID string, status string) error {
+	txn := dbConn.Txn(ctx, true)
+	defer txn.Abort()
+
+	user, err := get(txn, userID)
+	if err != nil {
+		return err
 	}
 
-	if amount > user.Balance {
-		return ErrInsufficientFunds
+	if !canTransition(user.Status, status) {
+		return errors.Wrapf(ErrInvalidTransition, "%s to %s", user.Status, status)
 	}
 
-	user.Balance = user.Balance - amount
+	if status == StatusClosed {
+		if user.Balance != 0 {
+			return ErrBalanceNotZero
+		}
+		if err := forfeitBonus(txn, userID, time.Now()); err != nil {
+			return err
+		}
+		if user, err = get(txn, userID); err != nil {
+			return err
+		}
+	}
+
+	user.Status = status
 
 	if err := txn.Insert("user", user); err != nil {
 		return errors.Wrap(err, "txn.Insert")
@@ -107,27 +337,95 @@ func WithdrawByID(ctx context.Context, dbConn *db.DB, userID string, amount int6
 	return nil
 }
 
-func GetBalanceByID(ctx context.Context, dbConn *db.DB, userID string) (int64, error) {
-	txn := dbConn.Txn(false)
+// TransferByID moves amount between two accounts of the same currency. The
+// sender pays the fee on top.
+func TransferByID(ctx context.Context, dbConn *db.DB, fromID, toID string, amount int64) (*ledger.Transaction, error) {
+	txn := dbConn.Txn(ctx, true)
 	defer txn.Abort()
 
-	raw, err := txn.First("user", "id", userID)
+	t, err := transfer(txn, fromID, toID, amount, time.Now())
 	if err != nil {
-		return 0, errors.Wrap(err, "txn.First")
+		metrics.Operation(ledger.TypeTransfer, outcome(err), amount)
+		return nil, err
 	}
 
-	user, ok := raw.(User)
-	if !ok {
-		return 0, errors.New("couldn't type assert user")
+	txn.Commit()
+	metrics.Operation(ledger.TypeTransfer, metrics.OutcomeOK, amount)
+
+	return &t, nil
+}
+
+// QuoteFeeByID returns the fee the user would be charged for moving amount
+// with the op transaction type.
+func QuoteFeeByID(ctx context.Context, dbConn *db.DB, userID string, op string, amount int64) (*fee.Quote, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	user, err := get(txn, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fee.Calculate(txn, op, user.Currency, amount)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return &fee.Quote{
+		Op:       op,
+		Currency: user.Currency,
+		Amount:   amount,
+		Fee:      f,
+		Total:    amount + f,
+	}, nil
+}
+
+func GetBalanceByID(ctx context.Context, dbConn *db.DB, userID string) (int64, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	user, err := get(txn, userID)
+	if err != nil {
+		return 0, err
 	}
 
 	return user.Balance, nil
 }
 
+// GetBalanceAtByID returns the user's balance as it was at the given moment.
+func GetBalanceAtByID(ctx context.Context, dbConn *db.DB, userID string, at time.Time) (int64, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	if _, err := get(txn, userID); err != nil {
+		return 0, err
+	}
+
+	return ledger.BalanceAt(txn, userID, at)
+}
+
+// GetBalanceRangeByID returns the user's opening and closing balances for
+// [from, to) and the movements in between.
+func GetBalanceRangeByID(ctx context.Context, dbConn *db.DB, userID string, from, to time.Time) (*ledger.Range, error) {
+	txn := dbConn.Txn(ctx, false)
+	defer txn.Abort()
+
+	if _, err := get(txn, userID); err != nil {
+		return nil, err
+	}
+
+	r, err := ledger.RangeOf(txn, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
 func List(ctx context.Context, dbConn *db.DB) ([]User, error) {
 	var users []User
 
-	txn := dbConn.Txn(false)
+	txn := dbConn.Txn(ctx, false)
 	defer txn.Abort()
 
 	it, err := txn.Get("user", "id")
@@ -140,6 +438,9 @@ func List(ctx context.Context, dbConn *db.DB) ([]User, error) {
 		if !ok {
 			return nil, errors.New("couldn't type assert user")
 		}
+		if u.DeletedAt != nil {
+			continue
+		}
 		users = append(users, u)
 	}
 
@@ -158,3 +459,312 @@ func ListLeaders(ctx context.Context, dbConn *db.DB) ([]User, error) {
 
 	return users, nil
 }
+
+func insert(txn *memdb.Txn, u User, now time.Time) (User, error) {
+	if err := checkEmail(txn, "", u.Email); err != nil {
+		return User{}, err
+	}
+
+	u.ID = uuid.New().String()
+	u.Currency = strings.ToUpper(u.Currency)
+	if u.Currency == "" {
+		u.Currency = DefaultCurrency
+	}
+	u.Balance = 0
+	u.Held = 0
+	u.Bonus = 0
+	u.Status = StatusActive
+	u.CreatedAt = now
+	u.DeletedAt = nil
+
+	if err := txn.Insert("user", u); err != nil {
+		return User{}, errors.Wrap(err, "txn.Insert")
+	}
+
+	return u, nil
+}
+
+func get(txn *memdb.Txn, userID string) (User, error) {
+	raw, err := txn.First("user", "id", userID)
+	if err != nil {
+		return User{}, errors.Wrap(err, "txn.First")
+	}
+	if raw == nil {
+		return User{}, ErrNotFound
+	}
+
+	user, ok := raw.(User)
+	if !ok {
+		return User{}, errors.New("couldn't type assert user")
+	}
+
+	return user, nil
+}
+
+func deposit(txn *memdb.Txn, userID string, amount int64, now time.Time) (ledger.Transaction, error) {
+	user, err := get(txn, userID)
+	if err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	if err := user.canReceive(); err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	if err := limit.Check(txn, userID, ledger.TypeDeposit, amount, now); err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	res, err := risk.Evaluate(txn, risk.Request{
+		Op:               ledger.TypeDeposit,
+		UserID:           userID,
+		Amount:           amount,
+		AccountCreatedAt: user.CreatedAt,
+		Now:              now,
+	})
+	if err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	user.Balance = user.Balance + amount
+
+	if err := txn.Insert("user", user); err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "txn.Insert")
+	}
+
+	t, err := ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypeDeposit,
+		UserID: userID,
+		Amount: amount,
+		Postings: []ledger.Posting{
+			{AccountID: userID, Amount: amount},
+			{AccountID: ledger.External, Amount: -amount},
+		},
+		Risk:       res.Decision,
+		RiskRuleID: res.RuleID,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "ledger.Record")
+	}
+
+	return t, nil
+}
+
+func withdraw(txn *memdb.Txn, userID string, amount int64, now time.Time) (ledger.Transaction, error) {
+	user, err := get(txn, userID)
+	if err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	if err := user.canSend(); err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	f, err := fee.Calculate(txn, ledger.TypeWithdraw, user.Currency, amount)
+	if err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "")
+	}
+
+	if amount+f > user.Available() {
+		metrics.InsufficientFunds(ledger.TypeWithdraw)
+		return ledger.Transaction{}, ErrInsufficientFunds
+	}
+
+	if err := limit.Check(txn, userID, ledger.TypeWithdraw, amount, now); err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	res, err := risk.Evaluate(txn, risk.Request{
+		Op:               ledger.TypeWithdraw,
+		UserID:           userID,
+		Amount:           amount,
+		AccountCreatedAt: user.CreatedAt,
+		Now:              now,
+	})
+	if err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	user.Balance = user.Balance - amount - f
+
+	if err := txn.Insert("user", user); err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "txn.Insert")
+	}
+
+	t, err := ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypeWithdraw,
+		UserID: userID,
+		Amount: amount,
+		Fee:    f,
+		Postings: withFee([]ledger.Posting{
+			{AccountID: userID, Amount: -amount - f},
+			{AccountID: ledger.External, Amount: amount},
+		}, f),
+		Risk:       res.Decision,
+		RiskRuleID: res.RuleID,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "ledger.Record")
+	}
+
+	return t, nil
+}
+
+func transfer(txn *memdb.Txn, fromID, toID string, amount int64, now time.Time) (ledger.Transaction, error) {
+	if fromID == toID {
+		return ledger.Transaction{}, ErrSameAccount
+	}
+
+	from, err := get(txn, fromID)
+	if err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	to, err := get(txn, toID)
+	if err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	if err := from.canSend(); err != nil {
+		return ledger.Transaction{}, err
+	}
+	if err := to.canReceive(); err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	if from.Currency != to.Currency {
+		return ledger.Transaction{}, ErrCurrencyMismatch
+	}
+
+	f, err := fee.Calculate(txn, ledger.TypeTransfer, from.Currency, amount)
+	if err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "")
+	}
+
+	if amount+f > from.Available() {
+		metrics.InsufficientFunds(ledger.TypeTransfer)
+		return ledger.Transaction{}, ErrInsufficientFunds
+	}
+
+	if err := limit.Check(txn, fromID, ledger.TypeTransfer, amount, now); err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	res, err := risk.Evaluate(txn, risk.Request{
+		Op:               ledger.TypeTransfer,
+		UserID:           fromID,
+		CounterpartyID:   toID,
+		Amount:           amount,
+		AccountCreatedAt: from.CreatedAt,
+		Now:              now,
+	})
+	if err != nil {
+		return ledger.Transaction{}, err
+	}
+
+	from.Balance = from.Balance - amount - f
+	to.Balance = to.Balance + amount
+
+	if err := txn.Insert("user", from); err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "txn.Insert")
+	}
+	if err := txn.Insert("user", to); err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "txn.Insert")
+	}
+
+	t, err := ledger.Record(txn, ledger.Transaction{
+		Type:   ledger.TypeTransfer,
+		UserID: fromID,
+		Amount: amount,
+		Fee:    f,
+		Postings: withFee([]ledger.Posting{
+			{AccountID: fromID, Amount: -amount - f},
+			{AccountID: toID, Amount: amount},
+		}, f),
+		Risk:       res.Decision,
+		RiskRuleID: res.RuleID,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return ledger.Transaction{}, errors.Wrap(err, "ledger.Record")
+	}
+
+	return t, nil
+}
+
+// withFee adds the posting collecting fee to the revenue account.
+func withFee(postings []ledger.Posting, fee int64) []ledger.Posting {
+	if fee == 0 {
+		return postings
+	}
+
+	return append(postings, ledger.Posting{AccountID: ledger.Revenue, Amount: fee})
+}
+
+// checkEmail returns ErrEmailTaken if a user other than userID already has
+// the email.
+func checkEmail(txn *memdb.Txn, userID string, email string) error {
+	if email == "" {
+		return nil
+	}
+
+	raw, err := txn.First("user", "email", email)
+	if err != nil {
+		return errors.Wrap(err, "txn.First")
+	}
+
+	if u, ok := raw.(User); ok && u.ID != userID {
+		return ErrEmailTaken
+	}
+
+	return nil
+}
+
+// outcome classifies the result of an operation for the metrics.
+func outcome(err error) string {
+	switch errors.Cause(err) {
+	case nil:
+		return metrics.OutcomeOK
+	case ErrInsufficientFunds:
+		return metrics.OutcomeInsufficientFunds
+	case ErrNotFound, ErrSameAccount, ErrAccountFrozen, ErrAccountSuspended, ErrAccountClosed,
+		ErrCurrencyMismatch, limit.ErrLimitExceeded, risk.ErrDenied:
+		return metrics.OutcomeRejected
+	}
+	return metrics.OutcomeError
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Available is the part of the balance that isn't reserved by holds.
+func (u User) Available() int64 {
+	return u.Balance - u.Held
+}
+
+// canReceive returns an error unless the account is allowed to be credited.
+func (u User) canReceive() error {
+	switch u.Status {
+	case StatusSuspended:
+		return ErrAccountSuspended
+	case StatusClosed:
+		return ErrAccountClosed
+	}
+	return nil
+}
+
+// canSend returns an error unless the account is allowed to be debited.
+func (u User) canSend() error {
+	if u.Status == StatusFrozen {
+		return ErrAccountFrozen
+	}
+	return u.canReceive()
+}
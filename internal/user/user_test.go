@@ -6,6 +6,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/timurguseynov/go-wallet-api/internal/tests"
 	"github.com/timurguseynov/go-wallet-api/internal/user"
@@ -39,6 +40,12 @@ func TestUser(t *testing.T) {
 	t.Run("userDepositByID", userDepositByID)
 	t.Run("userWithdrawByID", userWithdrawByID)
 	t.Run("userList", userList)
+	t.Run("userNotFound", userNotFound)
+	t.Run("userSearch", userSearch)
+	t.Run("userDeleteWithBalance", userDeleteWithBalance)
+	t.Run("userStatusFrozen", userStatusFrozen)
+	t.Run("userStatusSuspended", userStatusSuspended)
+	t.Run("userStatusClosed", userStatusClosed)
 }
 
 func userInsert(t *testing.T) {
@@ -52,7 +59,7 @@ func userInsert(t *testing.T) {
 }
 
 func userDepositByID(t *testing.T) {
-	err := user.DepositByID(ctx, test.MasterDB, userID, depositAmount)
+	_, err := user.DepositByID(ctx, test.MasterDB, userID, depositAmount)
 	assert.NoError(t, err)
 
 	balance, err := user.GetBalanceByID(ctx, test.MasterDB, userID)
@@ -61,7 +68,7 @@ func userDepositByID(t *testing.T) {
 }
 
 func userWithdrawByID(t *testing.T) {
-	err := user.WithdrawByID(ctx, test.MasterDB, userID, withdrawAmount)
+	_, err := user.WithdrawByID(ctx, test.MasterDB, userID, withdrawAmount)
 	assert.NoError(t, err)
 
 	balance, err := user.GetBalanceByID(ctx, test.MasterDB, userID)
@@ -69,6 +76,81 @@ func userWithdrawByID(t *testing.T) {
 	assert.Equal(t, depositAmount-withdrawAmount, balance)
 }
 
+func userStatusFrozen(t *testing.T) {
+	err := user.SetStatusByID(ctx, test.MasterDB, userID, user.StatusFrozen)
+	assert.NoError(t, err)
+
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, depositAmount)
+	assert.NoError(t, err, "frozen accounts can receive deposits")
+
+	_, err = user.WithdrawByID(ctx, test.MasterDB, userID, withdrawAmount)
+	assert.Equal(t, user.ErrAccountFrozen, err)
+}
+
+func userStatusSuspended(t *testing.T) {
+	err := user.SetStatusByID(ctx, test.MasterDB, userID, user.StatusSuspended)
+	assert.NoError(t, err)
+
+	_, err = user.DepositByID(ctx, test.MasterDB, userID, depositAmount)
+	assert.Equal(t, user.ErrAccountSuspended, err)
+
+	_, err = user.WithdrawByID(ctx, test.MasterDB, userID, withdrawAmount)
+	assert.Equal(t, user.ErrAccountSuspended, err)
+}
+
+func userStatusClosed(t *testing.T) {
+	err := user.SetStatusByID(ctx, test.MasterDB, userID, user.StatusClosed)
+	assert.Equal(t, user.ErrBalanceNotZero, err)
+
+	err = user.SetStatusByID(ctx, test.MasterDB, userID, user.StatusActive)
+	assert.NoError(t, err)
+
+	balance, err := user.GetBalanceByID(ctx, test.MasterDB, userID)
+	assert.NoError(t, err)
+	_, err = user.WithdrawByID(ctx, test.MasterDB, userID, balance)
+	assert.NoError(t, err)
+
+	err = user.SetStatusByID(ctx, test.MasterDB, userID, user.StatusClosed)
+	assert.NoError(t, err)
+
+	err = user.SetStatusByID(ctx, test.MasterDB, userID, user.StatusActive)
+	assert.Equal(t, user.ErrInvalidTransition, errors.Cause(err), "closed accounts can't be reopened")
+}
+
+func userSearch(t *testing.T) {
+	_, err := user.Insert(ctx, test.MasterDB, user.User{Name: "Searchable", Email: "search@example.com"})
+	assert.NoError(t, err)
+
+	_, err = user.Insert(ctx, test.MasterDB, user.User{Name: "Other", Email: "SEARCH@example.com"})
+	assert.Equal(t, user.ErrEmailTaken, err)
+
+	users, total, err := user.Search(ctx, test.MasterDB, user.Query{Text: "SEARCH"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, "Searchable", users[0].Name)
+
+	users, total, err = user.Search(ctx, test.MasterDB, user.Query{Limit: 2, Offset: 1})
+	assert.NoError(t, err)
+	assert.True(t, total > 3)
+	assert.Equal(t, 2, len(users))
+}
+
+func userDeleteWithBalance(t *testing.T) {
+	err := user.DeleteByID(ctx, test.MasterDB, userID)
+	assert.Equal(t, user.ErrBalanceNotZero, err)
+}
+
+func userNotFound(t *testing.T) {
+	_, err := user.GetBalanceByID(ctx, test.MasterDB, "unknown")
+	assert.Equal(t, user.ErrNotFound, err)
+
+	_, err = user.WithdrawByID(ctx, test.MasterDB, "unknown", withdrawAmount)
+	assert.Equal(t, user.ErrNotFound, err)
+
+	_, err = user.GetByEmail(ctx, test.MasterDB, "unknown@example.com")
+	assert.Equal(t, user.ErrNotFound, err)
+}
+
 func userList(t *testing.T) {
 	users, err := user.List(ctx, test.MasterDB)
 	assert.NoError(t, err)